@@ -22,6 +22,10 @@ type Value struct {
 	// the literal type(Number, String, Bool and Null) stores them by value
 	// the Object and Array stores them by pointer
 	AstValue interface{}
+
+	// Span is the location of the Value in the json document,
+	// it's nil unless the Parser is created with WithSpans.
+	Span *Span
 }
 
 //go:generate stringer -type=numberType
@@ -40,6 +44,9 @@ type NumberAst struct {
 	f  float64
 	u  uint64
 	i  int64
+
+	// literal is the original text kept by WithNumberLiteral
+	literal string
 }
 
 // GetInt64 returns the KvMap inside a NumberAst, it's possible to
@@ -87,8 +94,101 @@ type NullAst struct{}
 type BoolAst bool
 type StringAst string
 
+// Member is a key-value pair inside an object.
+type Member struct {
+	Key   string
+	Value Value
+}
+
+// ObjectAst stores the members of an object in the order they occur in the json document.
+// The zero value is an empty object ready to use.
 type ObjectAst struct {
-	KvMap map[string]Value
+	members []Member
+	// index maps a key to its position inside members
+	index map[string]int
+
+	// keySpans stores the locations of keys, it's nil unless the Parser records spans
+	keySpans map[string]Span
+}
+
+// NewObjectAst creates an object with members in order. The latter one overrides the
+// former one if they share the same key.
+func NewObjectAst(members ...Member) *ObjectAst {
+	o := &ObjectAst{index: make(map[string]int, len(members))}
+	for _, m := range members {
+		o.Set(m.Key, m.Value)
+	}
+	return o
+}
+
+// Len returns the number of members.
+func (o *ObjectAst) Len() int {
+	return len(o.members)
+}
+
+// Keys returns all keys in order.
+func (o *ObjectAst) Keys() []string {
+	keys := make([]string, 0, len(o.members))
+	for _, m := range o.members {
+		keys = append(keys, m.Key)
+	}
+	return keys
+}
+
+// Get returns the value of key, the returned pointer refers to the value stored inside
+// the object, so changes through it are visible to the object.
+func (o *ObjectAst) Get(key string) (*Value, bool) {
+	i, ok := o.index[key]
+	if !ok {
+		return nil, false
+	}
+	return &o.members[i].Value, true
+}
+
+// Set appends a new member at the end, or replaces the value in place if the key exists.
+func (o *ObjectAst) Set(key string, value Value) {
+	if i, ok := o.index[key]; ok {
+		o.members[i].Value = value
+		return
+	}
+	if o.index == nil {
+		o.index = make(map[string]int)
+	}
+	o.index[key] = len(o.members)
+	o.members = append(o.members, Member{Key: key, Value: value})
+}
+
+// Delete removes the member of key and keeps the order of the others.
+// It reports whether the key existed.
+func (o *ObjectAst) Delete(key string) bool {
+	i, ok := o.index[key]
+	if !ok {
+		return false
+	}
+	o.members = append(o.members[:i], o.members[i+1:]...)
+	delete(o.index, key)
+	delete(o.keySpans, key)
+	for ; i < len(o.members); i++ {
+		o.index[o.members[i].Key] = i
+	}
+	return true
+}
+
+// Range calls f for each member in order until f returns false.
+// The value pointer refers to the value stored inside the object.
+func (o *ObjectAst) Range(f func(key string, value *Value) bool) {
+	for i := range o.members {
+		if !f(o.members[i].Key, &o.members[i].Value) {
+			return
+		}
+	}
+}
+
+// KeySpan returns the location of the key in the json document.
+// It reports false if the key doesn't exist or the Parser doesn't record spans.
+func (o *ObjectAst) KeySpan(key string) (Span, bool) {
+	span, ok := o.keySpans[key]
+	return span, ok
 }
 
 type ArrayAst struct {
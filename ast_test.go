@@ -3,6 +3,7 @@ package astjson
 import (
 	"github.com/stretchr/testify/assert"
 	"math"
+	"strings"
 	"testing"
 )
 
@@ -420,3 +421,140 @@ func TestNumberAst_GetFloat64(t *testing.T) {
 	}
 
 }
+
+func TestObjectAst_Order(t *testing.T) {
+	val := Parse([]byte(`{"c": 1, "a": 2, "b": 3}`))
+	obj := GetObject(val)
+	assert.Equal(t, 3, obj.Len())
+	assert.Equal(t, []string{"c", "a", "b"}, obj.Keys())
+
+	var keys []string
+	obj.Range(func(key string, value *Value) bool {
+		keys = append(keys, key)
+		return key != "a"
+	})
+	assert.Equal(t, []string{"c", "a"}, keys)
+
+	a, ok := obj.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), GetNumber(a).GetUint64())
+	_, ok = obj.Get("d")
+	assert.False(t, ok)
+
+	// changes through the pointer are visible to the object
+	*a = Value{NodeType: Bool, AstValue: BoolAst(true)}
+	a, _ = obj.Get("a")
+	assert.True(t, GetBool(a))
+
+	// set an existing key keeps its position
+	obj.Set("c", Value{NodeType: Null, AstValue: &NullAst{}})
+	obj.Set("d", Value{NodeType: Null, AstValue: &NullAst{}})
+	assert.Equal(t, []string{"c", "a", "b", "d"}, obj.Keys())
+
+	assert.True(t, obj.Delete("a"))
+	assert.False(t, obj.Delete("a"))
+	assert.Equal(t, []string{"c", "b", "d"}, obj.Keys())
+	b, _ := obj.Get("b")
+	assert.Equal(t, uint64(3), GetNumber(b).GetUint64())
+	d, _ := obj.Get("d")
+	assert.True(t, IsNull(d))
+
+	assert.Equal(t, map[string]Value{
+		"c": {NodeType: Null, AstValue: &NullAst{}},
+		"b": {NodeType: Number, AstValue: NumberAst{Nt: unsignedInteger, u: 3}},
+		"d": {NodeType: Null, AstValue: &NullAst{}},
+	}, GetObjectKvMap(val))
+}
+
+func TestObjectAst_ZeroValue(t *testing.T) {
+	var obj ObjectAst
+	assert.Equal(t, 0, obj.Len())
+	assert.Empty(t, obj.Keys())
+	_, ok := obj.Get("a")
+	assert.False(t, ok)
+	assert.False(t, obj.Delete("a"))
+
+	obj.Set("a", Value{NodeType: String, AstValue: StringAst("a")})
+	assert.Equal(t, []string{"a"}, obj.Keys())
+
+	assert.Equal(t, NewObjectAst(Member{Key: "a", Value: Value{NodeType: String, AstValue: StringAst("a")}}), &obj)
+}
+
+func TestEqual(t *testing.T) {
+	testCases := map[string]struct {
+		a, b     string
+		expected bool
+	}{
+		"same literals":          {a: `"a"`, b: `"a"`, expected: true},
+		"different types":        {a: `1`, b: `"1"`, expected: false},
+		"integer and float":      {a: `1`, b: `1.0`, expected: true},
+		"negative zero":          {a: `-0`, b: `0`, expected: true},
+		"large integers":         {a: `9007199254740993`, b: `9007199254740992`, expected: false},
+		"negative and unsigned":  {a: `-1`, b: `18446744073709551615`, expected: false},
+		"null":                   {a: `null`, b: `null`, expected: true},
+		"arrays in order":        {a: `[1, [2]]`, b: `[1, [2]]`, expected: true},
+		"arrays in other order":  {a: `[1, 2]`, b: `[2, 1]`, expected: false},
+		"objects in other order": {a: `{"a": 1, "b": [true]}`, b: `{"b": [true], "a": 1.0}`, expected: true},
+		"objects with other key": {a: `{"a": 1}`, b: `{"b": 1}`, expected: false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Equal(Parse([]byte(tc.a)), Parse([]byte(tc.b))))
+		})
+	}
+}
+
+func TestNumberAst_NumberLiteral(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		str      string
+		bigInt   string
+		isInt    bool
+		bigFloat string
+		exact    bool
+	}{
+		"integer":         {input: `-42`, str: "-42", bigInt: "-42", isInt: true, bigFloat: "-42", exact: true},
+		"beyond uint64":   {input: `18446744073709551617`, str: "18446744073709551617", bigInt: "18446744073709551617", isInt: true, bigFloat: "1.8446744073709551617e+19", exact: false},
+		"integral float":  {input: `1.0e2`, str: "1.0e2", bigInt: "100", isInt: true, bigFloat: "100", exact: true},
+		"fraction":        {input: `0.1`, str: "0.1", isInt: false, bigFloat: "0.1", exact: false},
+		"exact fraction":  {input: `0.5`, str: "0.5", isInt: false, bigFloat: "0.5", exact: true},
+		"beyond float64":  {input: `1e400`, str: "1e400", bigInt: "1" + strings.Repeat("0", 400), isInt: true, bigFloat: "1e+400", exact: false},
+		"many digits":     {input: `3.14159265358979323846264338327950288`, str: "3.14159265358979323846264338327950288", isInt: false, bigFloat: "3.14159265358979323846264338327950288", exact: false},
+		"huge exponent":   {input: `1e99999`, str: "1e99999", isInt: false, exact: false},
+		"negative zero":   {input: `-0`, str: "-0", bigInt: "0", isInt: true, bigFloat: "-0", exact: true},
+		"tiny but finite": {input: `5e-324`, str: "5e-324", isInt: false, bigFloat: "5e-324", exact: false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			val, err := NewParser([]byte(tc.input), WithNumberLiteral()).ParseWithError()
+			assert.NoError(t, err)
+			n := GetNumber(val)
+			assert.Equal(t, tc.str, n.String())
+			assert.Equal(t, tc.exact, n.Exact())
+
+			bi, ok := n.BigInt()
+			assert.Equal(t, tc.isInt, ok)
+			if tc.isInt {
+				assert.Equal(t, tc.bigInt, bi.String())
+			}
+			if tc.bigFloat == "" {
+				assert.Nil(t, n.BigFloat())
+			} else {
+				assert.Equal(t, tc.bigFloat, n.BigFloat().Text('g', -1))
+			}
+		})
+	}
+}
+
+func TestNumberAst_String(t *testing.T) {
+	// the number without literal text is formatted by its stored value
+	assert.Equal(t, "18446744073709551615", NumberAst{Nt: unsignedInteger, u: math.MaxUint64}.String())
+	assert.Equal(t, "-1", NumberAst{Nt: integer, i: -1}.String())
+	assert.Equal(t, "0.1", NumberAst{Nt: floatNumber, f: 0.1}.String())
+	assert.True(t, NumberAst{Nt: floatNumber, f: 0.1}.Exact())
+	assert.Equal(t, "1/10", NumberAst{Nt: floatNumber, f: 0.1}.Rat().String())
+
+	// out of range number is rejected without the literal text
+	_, err := ParseBytes([]byte(`1e400`))
+	assert.ErrorIs(t, err, ErrInvalidNumber)
+}
@@ -31,11 +31,23 @@ func GetArrayValues(value *Value) []Value {
 	return value.AstValue.(*ArrayAst).Values
 }
 
-func GetObjectKvMap(value *Value) map[string]Value {
+func GetObject(value *Value) *ObjectAst {
 	if !IsObject(value) {
 		panic("value is not an object")
 	}
-	return value.AstValue.(*ObjectAst).KvMap
+	return value.AstValue.(*ObjectAst)
+}
+
+// GetObjectKvMap returns a copy of the object members in a map, the order of keys is lost
+// and changes on the map are invisible to the object. Use GetObject to access the object directly.
+func GetObjectKvMap(value *Value) map[string]Value {
+	obj := GetObject(value)
+	kvMap := make(map[string]Value, obj.Len())
+	obj.Range(func(key string, value *Value) bool {
+		kvMap[key] = *value
+		return true
+	})
+	return kvMap
 }
 
 func GetString(value *Value) string {
@@ -58,3 +70,60 @@ func GetNumber(value *Value) NumberAst {
 	}
 	return value.AstValue.(NumberAst)
 }
+
+// Equal reports whether a and b represent the same json value. Numbers are compared by
+// their values so 1 equals 1.0, the order of object members doesn't matter.
+func Equal(a, b *Value) bool {
+	if a.NodeType != b.NodeType {
+		return false
+	}
+	switch a.NodeType {
+	case Null:
+		return true
+	case Bool:
+		return GetBool(a) == GetBool(b)
+	case String:
+		return GetString(a) == GetString(b)
+	case Number:
+		return GetNumber(a).equal(GetNumber(b))
+	case Array:
+		as, bs := GetArrayValues(a), GetArrayValues(b)
+		if len(as) != len(bs) {
+			return false
+		}
+		for i := range as {
+			if !Equal(&as[i], &bs[i]) {
+				return false
+			}
+		}
+		return true
+	case Object:
+		ao, bo := GetObject(a), GetObject(b)
+		if ao.Len() != bo.Len() {
+			return false
+		}
+		same := true
+		ao.Range(func(key string, av *Value) bool {
+			bv, ok := bo.Get(key)
+			same = ok && Equal(av, bv)
+			return same
+		})
+		return same
+	}
+	return false
+}
+
+// equal compares the integers exactly, and the others as float64.
+func (n NumberAst) equal(m NumberAst) bool {
+	switch {
+	case n.Nt == unsignedInteger && m.Nt == unsignedInteger:
+		return n.u == m.u
+	case n.Nt == integer && m.Nt == integer:
+		return n.i == m.i
+	case n.Nt == integer && m.Nt == unsignedInteger:
+		return n.i >= 0 && uint64(n.i) == m.u
+	case n.Nt == unsignedInteger && m.Nt == integer:
+		return m.i >= 0 && uint64(m.i) == n.u
+	}
+	return n.GetFloat64() == m.GetFloat64()
+}
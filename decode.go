@@ -1,196 +1,718 @@
 package astjson
 
 import (
+	"encoding"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"math/big"
 	"reflect"
+	"strconv"
+	"strings"
 )
 
 const (
 	jsonTAG = "json"
 )
 
-type Decoder struct{}
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// bigNumberTypes are decoded by setNumber precisely, instead of by their own unmarshal methods.
+var bigNumberTypes = map[reflect.Type]bool{
+	reflect.TypeOf(big.Int{}):   true,
+	reflect.TypeOf(big.Float{}): true,
+	reflect.TypeOf(big.Rat{}):   true,
+}
+
+// AstUnmarshaler is implemented by the types that decode the AST value themselves.
+// It's preferred to json.Unmarshaler, which needs the value to be marshaled to bytes again.
+type AstUnmarshaler interface {
+	UnmarshalAST(val *Value) error
+}
+
+// DecodeError describes a json value cannot be decoded into the go type, like json.UnmarshalTypeError.
+type DecodeError struct {
+	// Path is the json pointer of the value, it's empty for the top-level value
+	Path     string
+	NodeType NodeType
+	// Type is the go type of the destination
+	Type reflect.Type
+	// Span is the location of the value, it's nil unless the Parser is created with WithSpans
+	Span *Span
+	// Err describes the detail, it might be nil
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	msg := fmt.Sprintf("cannot unmarshal %s into %v", e.NodeType, e.Type)
+	if e.Path != "" {
+		msg += " at " + e.Path
+	}
+	if e.Span != nil {
+		msg += fmt.Sprintf(" (line %d, column %d)", e.Span.Start.Line, e.Span.Start.Column)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+type Decoder struct {
+	// useNumber reports whether to decode numbers into interface{} as NumberLiteral
+	useNumber bool
+}
 
 func NewDecoder() *Decoder {
 	d := &Decoder{}
 	return d
 }
 
+// UseNumber makes the Decoder decode a number into interface{} as a NumberLiteral instead of
+// a float64 like json.Decoder.UseNumber, hence no precision is lost.
+func (d *Decoder) UseNumber() *Decoder {
+	d.useNumber = true
+	return d
+}
+
 // Unmarshal decodes the AST to a structure.
+// The types implementing AstUnmarshaler, json.Unmarshaler or encoding.TextUnmarshaler decode
+// themselves in that preference. A *DecodeError is returned if a value cannot be set into the
+// corresponding go type.
+// The existing values in dest are decoded in place, so the fields and map entries absent
+// in the AST are kept like encoding/json.
 // This API is an EXPERIENTIAL one and might be removed in the future.
 func (d *Decoder) Unmarshal(val *Value, dest interface{}) error {
 	if !isPointer(dest) {
 		return errors.New("dest must be a pointer")
 	}
+	rv := reflect.ValueOf(dest)
+	if rv.IsNil() {
+		return errors.New("dest must be a non-nil pointer")
+	}
 
-	return d.unmarshal(val, dest)
+	return d.unmarshal(val, rv.Elem(), nil)
 }
 
-// todo: think about using reflect.Value instead of interface{} as the type of dest
-func (d *Decoder) unmarshal(val *Value, dest interface{}) error {
+// unmarshal sets val into the settable rv, the path is the reference tokens of val from the top-level value.
+func (d *Decoder) unmarshal(val *Value, rv reflect.Value, path []string) error {
 	if val == nil {
 		return errors.New("value is a nil pointer")
 	}
-	// todo: currently I only consider the valid conversion, need ensure behaviors if fail
+	// follow the pointers to the value, the nil ones are allocated. The pointer is set to nil by null.
+	// The unmarshaler found on the way takes over the decoding.
+	for {
+		if ok, err := callUnmarshaler(val, rv, path); ok {
+			return err
+		}
+		if val.NodeType == Null || rv.Kind() != reflect.Pointer {
+			break
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Interface {
+		return d.setInterface(val, rv, path)
+	}
 	switch val.NodeType {
 	case Number:
-		return setNumber(val, dest)
+		return setNumber(val, rv, path)
 	case String:
-		return setString(val, dest)
+		return setString(val, rv, path)
 	case Null:
-		return setNull(dest)
+		return setNull(rv)
 	case Bool:
-		return setBool(val, dest)
+		return setBool(val, rv, path)
 	case Array:
-		return setArray(val, dest)
+		return d.setArray(val, rv, path)
 	case Object:
-		return setObject(val, dest)
+		return d.setObject(val, rv, path)
 	}
 	return errors.New("invalid value")
 }
 
-func setObject(val *Value, dest interface{}) error {
-	obj := val.AstValue.(*ObjectAst)
-	// *T --> T
-	rval := reflect.ValueOf(dest).Elem()
-	rtyp := reflect.TypeOf(dest).Elem()
+// callUnmarshaler decodes val by the AstUnmarshaler, json.Unmarshaler or encoding.TextUnmarshaler
+// implemented by the pointer to rv, it reports false if none of them is implemented.
+// The TextUnmarshaler only accepts the strings, and it ignores null.
+func callUnmarshaler(val *Value, rv reflect.Value, path []string) (bool, error) {
+	if !rv.CanAddr() || rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		return false, nil
+	}
+	if val.NodeType == Number && bigNumberTypes[rv.Type()] {
+		return false, nil
+	}
 
 	var err error
-	for index := 0; index < rtyp.NumField(); index++ {
+	switch u := rv.Addr().Interface().(type) {
+	case AstUnmarshaler:
+		err = u.UnmarshalAST(val)
+	case json.Unmarshaler:
+		var bs []byte
+		if bs, err = Marshal(val); err == nil {
+			err = u.UnmarshalJSON(bs)
+		}
+	case encoding.TextUnmarshaler:
+		switch val.NodeType {
+		case Null:
+		case String:
+			err = u.UnmarshalText([]byte(GetString(val)))
+		default:
+			return true, typeError(val, rv, path, nil)
+		}
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, typeError(val, rv, path, err)
+	}
+	return true, nil
+}
 
-		fieldTyp := rtyp.Field(index)
-		tag := fieldTyp.Tag.Get(jsonTAG)
-		if tag == "" && !fieldTyp.Anonymous {
-			continue
+// typeError reports val at path cannot be set into rv, err is the optional detail.
+func typeError(val *Value, rv reflect.Value, path []string, err error) *DecodeError {
+	return &DecodeError{
+		Path:     formatPointer(path),
+		NodeType: val.NodeType,
+		Type:     rv.Type(),
+		Span:     val.Span,
+		Err:      err,
+	}
+}
+
+// childPath returns the path of a child value, it never modifies the underlying array of path
+// because the sibling values share it.
+func childPath(path []string, token string) []string {
+	return append(path[:len(path):len(path)], token)
+}
+
+// setInterface sets val into the interface rv, only the empty interface is supported.
+func (d *Decoder) setInterface(val *Value, rv reflect.Value, path []string) error {
+	if rv.NumMethod() != 0 {
+		return typeError(val, rv, path, errors.New("non-empty interface is unsupported"))
+	}
+	generic := d.interfaceValue(val)
+	if generic == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+	rv.Set(reflect.ValueOf(generic))
+	return nil
+}
+
+// interfaceValue converts val to the generic go value like encoding/json, that is,
+// map[string]interface{} for objects, []interface{} for arrays, float64 or NumberLiteral
+// for numbers, string, bool and nil for null.
+func (d *Decoder) interfaceValue(val *Value) interface{} {
+	switch val.NodeType {
+	case Object:
+		obj := val.AstValue.(*ObjectAst)
+		m := make(map[string]interface{}, obj.Len())
+		for _, key := range obj.Keys() {
+			v, _ := obj.Get(key)
+			m[key] = d.interfaceValue(v)
 		}
+		return m
+	case Array:
+		values := GetArrayValues(val)
+		ar := make([]interface{}, len(values))
+		for i := range values {
+			ar[i] = d.interfaceValue(&values[i])
+		}
+		return ar
+	case Number:
+		if d.useNumber {
+			return NumberLiteral(GetNumber(val).String())
+		}
+		return GetNumber(val).GetFloat64()
+	case String:
+		return GetString(val)
+	case Bool:
+		return GetBool(val)
+	}
+	return nil
+}
+
+// setObject sets the json object into a map or a struct. For the struct, the same rules of
+// encoding/json are followed, that is, every key is matched to the field with the same name,
+// or a case-insensitive one if not found. The unknown keys are ignored.
+func (d *Decoder) setObject(val *Value, rv reflect.Value, path []string) error {
+	obj := val.AstValue.(*ObjectAst)
+	if rv.Kind() == reflect.Map {
+		return d.setMap(obj, rv, path)
+	}
+	if rv.Kind() != reflect.Struct {
+		return typeError(val, rv, path, nil)
+	}
+	fields := structFields(rv.Type())
 
-		// handle embedded fields
-		if fieldTyp.Anonymous {
-			fieldVal := reflect.New(fieldTyp.Type)
-			err = NewDecoder().unmarshal(val, fieldVal.Interface())
+	for _, key := range obj.Keys() {
+		f, ok := matchField(fields, key)
+		if !ok {
+			continue
+		}
+		astVal, _ := obj.Get(key)
+		keyPath := childPath(path, key)
+		target, err := fieldByIndex(rv, f.index)
+		if err != nil {
+			return typeError(astVal, rv, keyPath, err)
+		}
+		if f.quoted {
+			quoted, err := unquoteValue(astVal, f.typ)
 			if err != nil {
-				return err
+				return typeError(astVal, target, keyPath, err)
 			}
+			astVal = quoted
+		}
 
-			rval.Field(index).Set(fieldVal.Elem())
-			continue
+		// the field is decoded in place like encoding/json, so the nested structs and maps
+		// are merged, and the existing pointers are kept
+		if err = d.unmarshal(astVal, target, keyPath); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		astVal := obj.KvMap[tag]
+// fieldByIndex returns the nested field of struct rv by index like reflect.Value.FieldByIndex,
+// but the nil pointers of embedded structs are allocated.
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !rv.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct %v", rv.Type().Elem())
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, nil
+}
 
-		// construct a pointer type of field type to store the data
-		// we cannot pass the field directly because it's not a reference but a value, however,
-		// we want to change the value itself. So pass it in side unmarshal as an interface doesn't work.
-		fieldVal := reflect.New(rtyp.Field(index).Type)
-		err = NewDecoder().unmarshal(&astVal, fieldVal.Interface())
+// setMap sets the members of obj into the map rv, the existing entries are kept wherever
+// the map is, and the value of a decoded key is replaced as a whole like encoding/json.
+// The key type of map should be a string, an integer or implement encoding.TextUnmarshaler.
+func (d *Decoder) setMap(obj *ObjectAst, rv reflect.Value, path []string) error {
+	typ := rv.Type()
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMapWithSize(typ, obj.Len()))
+	}
+	for _, key := range obj.Keys() {
+		astVal, _ := obj.Get(key)
+		keyPath := childPath(path, key)
+		kv, err := mapKey(key, typ.Key())
 		if err != nil {
+			return &DecodeError{Path: formatPointer(keyPath), NodeType: String, Type: typ.Key(), Err: err}
+		}
+		elem := reflect.New(typ.Elem()).Elem()
+		if err = d.unmarshal(astVal, elem, keyPath); err != nil {
 			return err
 		}
-
-		rval.Field(index).Set(fieldVal.Elem())
+		rv.SetMapIndex(kv, elem)
 	}
 	return nil
 }
 
-// setArray sets the json array into golang a slice or an array.
-func setArray(val *Value, dest interface{}) error {
-	ars := val.AstValue.(*ArrayAst).Values
+// mapKey converts the object key to a map key of type t in the same way as encoding/json.
+func mapKey(key string, t reflect.Type) (reflect.Value, error) {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		kv := reflect.New(t)
+		if err := kv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(key)); err != nil {
+			return reflect.Value{}, err
+		}
+		return kv.Elem(), nil
+	}
 
-	kind := reflect.TypeOf(dest).Elem().Kind()
-	if kind != reflect.Array && kind != reflect.Slice {
-		// todo: ignore error or report it?
-		return nil
+	kv := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		kv.SetString(key)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(key, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid key %q", key)
+		}
+		kv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(key, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid key %q", key)
+		}
+		kv.SetUint(u)
+	default:
+		return reflect.Value{}, errors.New("unsupported map key type")
 	}
+	return kv, nil
+}
+
+// field is a struct field which could be set by a json key.
+type field struct {
+	name string
+	// index is the index sequence for reflect.Value.FieldByIndex
+	index []int
+	typ   reflect.Type
+	// tagged reports whether the name is given by tag
+	tagged bool
+	// quoted reports whether the value is encoded inside a json string by the string option
+	quoted bool
+}
+
+// structFields returns the fields of struct type t which could be set by json keys, including
+// the ones promoted from embedded structs. The rules of encoding/json are followed:
+//   - the unexported fields and the fields tagged by "-" are ignored
+//   - the name is given by tag, or the field name if it's not tagged
+//   - the shallower field hides the deeper one with the same name, and the tagged one wins
+//     if they're at the same depth, otherwise all of them are ignored
+func structFields(t reflect.Type) []field {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	var (
+		fields  []field
+		current []embedded
+		next    = []embedded{{typ: t}}
+		visited = map[reflect.Type]bool{}
+	)
+	for len(next) > 0 {
+		current, next = next, nil
+		// the fields found at the current depth
+		var found []field
+		for _, e := range current {
+			// the same struct embedded at the same depth isn't skipped, so its fields conflict
+			if visited[e.typ] {
+				continue
+			}
+
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Pointer {
+					ft = ft.Elem()
+				}
+				if !sf.IsExported() && !(sf.Anonymous && ft.Kind() == reflect.Struct) {
+					continue
+				}
+				tag := sf.Tag.Get(jsonTAG)
+				if tag == "-" {
+					continue
+				}
+				name, opts := parseTag(tag)
+				index := append(append([]int{}, e.index...), i)
+
+				// the fields of an untagged embedded struct or pointer to struct are promoted
+				if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{typ: ft, index: index})
+					continue
+				}
+				if !sf.IsExported() {
+					continue
+				}
+
+				f := field{name: name, index: index, typ: sf.Type, tagged: name != ""}
+				if f.name == "" {
+					f.name = sf.Name
+				}
+				f.quoted = opts.contains("string") && quotable(sf.Type)
+				found = append(found, f)
+			}
+		}
+
+		for _, e := range current {
+			visited[e.typ] = true
+		}
+		for i := range found {
+			if dominantField(fields, found, i) {
+				fields = append(fields, found[i])
+			}
+		}
+	}
+	return fields
+}
+
+// dominantField reports whether the i-th field found at the current depth should be kept.
+// It's hidden by the shallower fields, or conflicts with the other fields with the same name
+// in found unless it's the only tagged one.
+func dominantField(shallower, found []field, i int) bool {
+	f := found[i]
+	for _, s := range shallower {
+		if s.name == f.name {
+			return false
+		}
+	}
+	for j, other := range found {
+		if j != i && other.name == f.name && (!f.tagged || other.tagged) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchField finds the field of key, the exact match is preferred to the case-insensitive one.
+func matchField(fields []field, key string) (field, bool) {
+	for _, f := range fields {
+		if f.name == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.name, key) {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// tagOptions is the options after the name in a json tag, such as "omitempty,string".
+type tagOptions string
+
+// parseTag splits a json tag into the name and the options.
+func parseTag(tag string) (string, tagOptions) {
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, tagOptions(opts)
+}
+
+// contains reports whether the option is set.
+func (o tagOptions) contains(option string) bool {
+	for o != "" {
+		opt, rest, _ := strings.Cut(string(o), ",")
+		if opt == option {
+			return true
+		}
+		o = tagOptions(rest)
+	}
+	return false
+}
+
+// quotable reports whether the string option applies to type t, only the strings, numbers
+// and bools can be quoted.
+func quotable(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// unquoteValue parses the json value inside the string val for the field of type t
+// tagged by the string option. The null is kept as it is.
+func unquoteValue(val *Value, t reflect.Type) (*Value, error) {
+	if val.NodeType == Null {
+		return val, nil
+	}
+	if val.NodeType == String {
+		inner, err := ParseBytes([]byte(GetString(val)), WithSingleDocument())
+		if err == nil && inner != nil && (inner.NodeType == Null || inner.NodeType == quotedType(t)) {
+			return inner, nil
+		}
+	}
+	return nil, errors.New("invalid use of ,string struct tag")
+}
 
-	boundary := reflect.ValueOf(dest).Elem().Len()
+// quotedType returns the node type expected inside the quoted string for type t.
+func quotedType(t reflect.Type) NodeType {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return Bool
+	case reflect.String:
+		return String
+	}
+	return Number
+}
 
-	for i, value := range ars {
+// setArray sets the json array into golang a slice or an array.
+func (d *Decoder) setArray(val *Value, rv reflect.Value, path []string) error {
+	kind := rv.Kind()
+	if kind != reflect.Array && kind != reflect.Slice {
+		return typeError(val, rv, path, nil)
+	}
+
+	values := GetArrayValues(val)
+	boundary := rv.Len()
+	for i := range values {
 		// all available fields in an array are filled, we needn't to continue
 		if i >= boundary && kind == reflect.Array {
 			return nil
 		}
 
-		// double elem get from a pointer of array to the array element type
-		// *[]T --Elem()--> []T --Elem()--> T
-		elemType := reflect.TypeOf(dest).Elem().Elem()
-		newVal := reflect.New(elemType)
-		err := NewDecoder().unmarshal(&value, newVal.Interface())
-		if err != nil {
+		elem := reflect.New(rv.Type().Elem()).Elem()
+		if err := d.unmarshal(&values[i], elem, childPath(path, strconv.Itoa(i))); err != nil {
 			return err
 		}
-		elem := newVal.Elem()
 
 		// this logic only applies to slice because array has a fixed length.
 		if i >= boundary {
-			// append the element into the array,
-			// instead of the pointer to the array
-			na := reflect.Append(reflect.ValueOf(dest).Elem(), elem)
-			reflect.ValueOf(dest).Elem().Set(na)
+			rv.Set(reflect.Append(rv, elem))
 			continue
 		}
-
-		reflect.ValueOf(dest).Elem().Index(i).Set(elem)
+		rv.Index(i).Set(elem)
 	}
 
 	return nil
 }
 
-func setNull(dest interface{}) error {
+func setNull(rv reflect.Value) error {
 	// pointer owns type, we cannot assign it a nil directly
-	v := reflect.ValueOf(dest).Elem()
-	v.Set(reflect.Zero(v.Type()))
+	rv.Set(reflect.Zero(rv.Type()))
 	return nil
 }
 
-func setBool(val *Value, dest interface{}) error {
-	reflect.ValueOf(dest).Elem().SetBool(bool(val.AstValue.(BoolAst)))
+func setBool(val *Value, rv reflect.Value, path []string) error {
+	if rv.Kind() != reflect.Bool {
+		return typeError(val, rv, path, nil)
+	}
+	rv.SetBool(bool(val.AstValue.(BoolAst)))
 	return nil
 }
 
-func setNumber(val *Value, dest interface{}) error {
-	kind := reflect.ValueOf(dest).Elem().Kind()
+// setNumber sets the number into rv which is an integer, a float, a big number or a NumberLiteral.
+// The number should be in the range of rv, and it should be an integer for the integer types.
+func setNumber(val *Value, rv reflect.Value, path []string) error {
 	numberAst := val.AstValue.(NumberAst)
-	switch kind {
+	if ok, err := setBigNumber(numberAst, rv.Addr().Interface()); ok {
+		if err != nil {
+			return typeError(val, rv, path, err)
+		}
+		return nil
+	}
+
+	switch rv.Kind() {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		reflect.ValueOf(dest).Elem().SetInt(numberAst.GetInt64())
+		i, ok := numberInt64(numberAst)
+		if !ok || rv.OverflowInt(i) {
+			return typeError(val, rv, path, integerError(numberAst))
+		}
+		rv.SetInt(i)
 		return nil
 
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		reflect.ValueOf(dest).Elem().SetUint(numberAst.GetUint64())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, ok := numberUint64(numberAst)
+		if !ok || rv.OverflowUint(u) {
+			return typeError(val, rv, path, integerError(numberAst))
+		}
+		rv.SetUint(u)
 		return nil
 
-	case reflect.Float32,
-		reflect.Float64:
-		reflect.ValueOf(dest).Elem().SetFloat(numberAst.GetFloat64())
+	case reflect.Float32, reflect.Float64:
+		f := numberAst.GetFloat64()
+		if math.IsInf(f, 0) || rv.OverflowFloat(f) {
+			return typeError(val, rv, path, fmt.Errorf("number %s overflows", numberAst))
+		}
+		rv.SetFloat(f)
 		return nil
 	}
-	panic("fail to set number")
+	return typeError(val, rv, path, nil)
 }
 
-// setString set the string value into dest
-// todo: support []byte and []int8
-// todo: think about whether support the implicitly cast from byte to the other types
-func setString(val *Value, dest interface{}) error {
-	v := reflect.ValueOf(dest).Elem()
-	kind := v.Kind()
+// integerError describes why n cannot be set into an integer type.
+func integerError(n NumberAst) error {
+	if n.Nt == floatNumber && n.f != math.Trunc(n.f) {
+		return fmt.Errorf("number %s isn't an integer", n)
+	}
+	return fmt.Errorf("number %s overflows", n)
+}
+
+// numberInt64 returns the int64 value of n, it reports false if n isn't an integer inside
+// the range of int64.
+func numberInt64(n NumberAst) (int64, bool) {
+	switch n.Nt {
+	case integer:
+		return n.i, true
+	case unsignedInteger:
+		return int64(n.u), n.u <= math.MaxInt64
+	}
+	// -2^63 is exact in float64, but 2^63 overflows
+	if n.f != math.Trunc(n.f) || n.f < math.MinInt64 || n.f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(n.f), true
+}
+
+// numberUint64 returns the uint64 value of n, it reports false if n isn't an integer inside
+// the range of uint64.
+func numberUint64(n NumberAst) (uint64, bool) {
+	switch n.Nt {
+	case integer:
+		return uint64(n.i), n.i >= 0
+	case unsignedInteger:
+		return n.u, true
+	}
+	if n.f != math.Trunc(n.f) || n.f < 0 || n.f >= math.MaxUint64 {
+		return 0, false
+	}
+	return uint64(n.f), true
+}
+
+// setBigNumber sets the precise number into dest when dest is a big number or a literal,
+// it reports false if dest isn't one of them.
+func setBigNumber(n NumberAst, dest interface{}) (bool, error) {
+	switch d := dest.(type) {
+	case *NumberLiteral:
+		*d = NumberLiteral(n.String())
+	case *json.Number:
+		*d = json.Number(n.String())
+	case *big.Int:
+		bi, ok := n.BigInt()
+		if !ok {
+			return true, fmt.Errorf("number %s isn't an integer", n)
+		}
+		d.Set(bi)
+	case *big.Float:
+		bf := n.BigFloat()
+		if bf == nil {
+			return true, fmt.Errorf("number %s overflows", n)
+		}
+		d.Set(bf)
+	case *big.Rat:
+		r := n.Rat()
+		if r == nil {
+			return true, fmt.Errorf("number %s overflows", n)
+		}
+		d.Set(r)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
 
-	strAst := val.AstValue.(StringAst)
-	switch kind {
+// setString sets the string value into a string, a byte slice or a byte array.
+// todo: think about whether support the implicitly cast from byte to the other types
+func setString(val *Value, rv reflect.Value, path []string) error {
+	str := string(val.AstValue.(StringAst))
+	switch rv.Kind() {
 	case reflect.String:
-		v.SetString(string(strAst))
+		rv.SetString(str)
 		return nil
 	case reflect.Slice:
-		v.SetBytes([]byte(strAst))
-		return nil
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			rv.SetBytes([]byte(str))
+			return nil
+		}
 	case reflect.Array:
-		l := v.Len()
-		bs := []byte(strAst)
-		for i := 0; i < l; i++ {
-			v.Index(i).Set(reflect.ValueOf(bs[i]))
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			// the extra bytes are dropped and the remained elements are zero
+			reflect.Copy(rv, reflect.ValueOf([]byte(str)))
+			return nil
 		}
-		return nil
 	}
-	panic("fail to set string")
+	return typeError(val, rv, path, nil)
 }
 
 func isPointer(dest interface{}) bool {
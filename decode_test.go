@@ -1,8 +1,15 @@
 package astjson
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -108,6 +115,37 @@ func Test_Unmarshal_Number(t *testing.T) {
 	}
 }
 
+func Test_Unmarshal_BigNumber(t *testing.T) {
+	type Amount struct {
+		Int     *big.Int      `json:"int"`
+		Float   *big.Float    `json:"float"`
+		Rat     big.Rat       `json:"rat"`
+		Literal NumberLiteral `json:"literal"`
+		Number  json.Number   `json:"number"`
+	}
+	input := `{"int": 123456789012345678901234567890, "float": 3.14159265358979323846264338327950288,
+"rat": 0.1, "literal": 1.50, "number": 1e400}`
+	val, err := NewParser([]byte(input), WithNumberLiteral()).ParseWithError()
+	assert.NoError(t, err)
+
+	var amount Amount
+	assert.NoError(t, NewDecoder().Unmarshal(val, &amount))
+	assert.Equal(t, "123456789012345678901234567890", amount.Int.String())
+	assert.Equal(t, "3.14159265358979323846264338327950288", amount.Float.Text('g', -1))
+	assert.Equal(t, "1/10", amount.Rat.String())
+	assert.Equal(t, NumberLiteral("1.50"), amount.Literal)
+	assert.Equal(t, json.Number("1e400"), amount.Number)
+
+	f, err := amount.Literal.Float64()
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, f)
+
+	// a fraction cannot be set into big.Int
+	var bi big.Int
+	err = NewDecoder().Unmarshal(NewParser([]byte(`1.5`)).Parse(), &bi)
+	assert.EqualError(t, err, "cannot unmarshal Number into big.Int: number 1.5 isn't an integer")
+}
+
 func Test_Unmarshal_String_and_Slice(t *testing.T) {
 	makeslice := func(len int) *[]byte {
 		sl := make([]byte, len)
@@ -123,6 +161,11 @@ func Test_Unmarshal_String_and_Slice(t *testing.T) {
 			destination: new(string),
 			expected:    "helloworld",
 		},
+		"Escaped String": {
+			input:       `"hello\n\"world\" \u4f60\u597d"`,
+			destination: new(string),
+			expected:    "hello\n\"world\" \u4f60\u597d",
+		},
 		"Bytes Slice With Enough Space": {
 			input:       `"hello-world"`,
 			destination: makeslice(12),
@@ -390,3 +433,425 @@ func Test_Simple_Object(t *testing.T) {
 	assert.NoError(t, NewDecoder().Unmarshal(NewParser([]byte(jsonStr)).Parse(), &d))
 	assert.Equal(t, expected, d)
 }
+
+func Test_Unmarshal_MergeStruct(t *testing.T) {
+	type (
+		In struct {
+			A, B int
+		}
+		Out struct {
+			In    In
+			Embed struct{ In }
+			Num   int
+		}
+	)
+	input := `{"In": {"A": 10}, "Embed": {"B": 20}}`
+	expected := Out{In: In{A: 1, B: 2}, Num: 3}
+	expected.Embed.In = In{A: 4, B: 5}
+	assert.NoError(t, json.Unmarshal([]byte(input), &expected))
+
+	// the nested structs are merged like encoding/json instead of replaced
+	actual := Out{In: In{A: 1, B: 2}, Num: 3}
+	actual.Embed.In = In{A: 4, B: 5}
+	assert.NoError(t, NewDecoder().Unmarshal(Parse([]byte(input)), &actual))
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, In{A: 10, B: 2}, actual.In)
+	assert.Equal(t, In{A: 4, B: 20}, actual.Embed.In)
+}
+
+func Test_Unmarshal_StructTags(t *testing.T) {
+	type (
+		Base struct {
+			ID    int    `json:"id"`
+			Name  string `json:"name"`
+			Level int
+		}
+		inner struct {
+			Hidden string
+		}
+		Other struct {
+			Level int
+		}
+		Tagged struct {
+			Value string `json:"value"`
+		}
+		demo struct {
+			Base
+			inner
+			Other
+			Tagged   `json:"tagged"`
+			Name     string  `json:"name,omitempty"`
+			Skipped  string  `json:"-"`
+			Dash     string  `json:"-,"`
+			Untagged string  ``
+			Options  string  `json:",omitempty"`
+			Count    int     `json:"count,string"`
+			Ratio    float64 `json:"ratio,omitempty,string"`
+			Enabled  *bool   `json:"enabled,string"`
+			Quoted   string  `json:"quoted,string"`
+			Ignored  []int   `json:"ignored,string"`
+			private  string
+		}
+	)
+
+	testCases := map[string]string{
+		"tag options":      `{"name": "alice", "-": "dash", "Skipped": "no", "Options": "opt"}`,
+		"field name":       `{"Untagged": "a", "Hidden": "promoted", "private": "no"}`,
+		"case-insensitive": `{"NAME": "alice", "untagged": "a", "ID": 1, "options": "opt"}`,
+		"exact first":      `{"untagged": "a", "Untagged": "b", "UNTAGGED": "c"}`,
+		"embedded":         `{"id": 1, "Level": 2, "tagged": {"value": "v"}, "value": "no"}`,
+		"quoted":           `{"count": "12", "ratio": "0.5", "enabled": "true", "quoted": "\"q\"", "ignored": [1]}`,
+		"quoted null":      `{"count": null, "enabled": "null"}`,
+	}
+	for name, input := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var expected demo
+			assert.NoError(t, json.Unmarshal([]byte(input), &expected))
+
+			var actual demo
+			assert.NoError(t, NewDecoder().Unmarshal(Parse([]byte(input)), &actual))
+			assert.Equal(t, expected, actual)
+		})
+	}
+
+	errCases := map[string]string{
+		"unquoted number": `{"count": 12}`,
+		"invalid quoted":  `{"count": "abc"}`,
+		"mismatched type": `{"enabled": "1"}`,
+		"unquoted string": `{"quoted": "q"}`,
+	}
+	for name, input := range errCases {
+		t.Run(name, func(t *testing.T) {
+			var expected demo
+			assert.Error(t, json.Unmarshal([]byte(input), &expected))
+
+			var actual demo
+			err := NewDecoder().Unmarshal(Parse([]byte(input)), &actual)
+			assert.ErrorContains(t, err, "invalid use of ,string struct tag")
+		})
+	}
+}
+
+type upperKey string
+
+func (k *upperKey) UnmarshalText(text []byte) error {
+	*k = upperKey(strings.ToUpper(string(text)))
+	return nil
+}
+
+func Test_Unmarshal_Map(t *testing.T) {
+	input := `{"a": 1, "10": 2, "-3": 3}`
+	testCases := map[string]struct {
+		destination interface{}
+		expected    interface{}
+	}{
+		"string key":            {destination: new(map[string]int), expected: map[string]int{"a": 1, "10": 2, "-3": 3}},
+		"text unmarshaler key":  {destination: new(map[upperKey]int), expected: map[upperKey]int{"A": 1, "10": 2, "-3": 3}},
+		"interface value":       {destination: new(map[string]interface{}), expected: map[string]interface{}{"a": 1.0, "10": 2.0, "-3": 3.0}},
+		"existing entries kept": {destination: &map[string]int{"b": 4, "a": 0}, expected: map[string]int{"a": 1, "b": 4, "10": 2, "-3": 3}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.NoError(t, NewDecoder().Unmarshal(Parse([]byte(input)), tc.destination))
+			assert.Equal(t, tc.expected, reflect.ValueOf(tc.destination).Elem().Interface())
+		})
+	}
+
+	intKeys := map[int8]string{}
+	assert.NoError(t, NewDecoder().Unmarshal(Parse([]byte(`{"1": "a", "-128": "b"}`)), &intKeys))
+	assert.Equal(t, map[int8]string{1: "a", -128: "b"}, intKeys)
+
+	uintKeys := map[uint]string{}
+	err := NewDecoder().Unmarshal(Parse([]byte(`{"-1": "a"}`)), &uintKeys)
+	assert.EqualError(t, err, `cannot unmarshal String into uint at /-1: invalid key "-1"`)
+
+	floatKeys := map[float64]string{}
+	err = NewDecoder().Unmarshal(Parse([]byte(`{"1": "a"}`)), &floatKeys)
+	assert.EqualError(t, err, "cannot unmarshal String into float64 at /1: unsupported map key type")
+
+	var nested struct {
+		Labels map[string][]string `json:"labels"`
+	}
+	assert.NoError(t, NewDecoder().Unmarshal(Parse([]byte(`{"labels": {"env": ["dev", "prod"]}}`)), &nested))
+	assert.Equal(t, map[string][]string{"env": {"dev", "prod"}}, nested.Labels)
+
+	// the map field is merged as well, and the values of the decoded keys are replaced
+	populated := struct {
+		Labels map[string]map[string]int `json:"labels"`
+	}{Labels: map[string]map[string]int{"a": {"x": 1}, "b": {"y": 2}}}
+	labels := populated.Labels
+	assert.NoError(t, NewDecoder().Unmarshal(Parse([]byte(`{"labels": {"a": {"z": 3}, "c": {}}}`)), &populated))
+	assert.Equal(t, map[string]map[string]int{"a": {"z": 3}, "b": {"y": 2}, "c": {}}, populated.Labels)
+	assert.Equal(t, reflect.ValueOf(labels).Pointer(), reflect.ValueOf(populated.Labels).Pointer())
+}
+
+func Test_Unmarshal_Interface(t *testing.T) {
+	input := `{"str": "a", "num": 1.5, "big": 12345678901234567890, "bool": true, "null": null,
+"arr": [1, "b", [], {}], "obj": {"k": false}}`
+
+	var expected interface{}
+	assert.NoError(t, json.Unmarshal([]byte(input), &expected))
+	var actual interface{}
+	assert.NoError(t, NewDecoder().Unmarshal(Parse([]byte(input)), &actual))
+	assert.Equal(t, expected, actual)
+
+	// the number literal is kept by UseNumber
+	var withNumber struct {
+		Dynamic interface{} `json:"dynamic"`
+	}
+	val := Parse([]byte(`{"dynamic": [1.50, 12345678901234567890]}`), WithNumberLiteral())
+	assert.NoError(t, NewDecoder().UseNumber().Unmarshal(val, &withNumber))
+	assert.Equal(t, []interface{}{NumberLiteral("1.50"), NumberLiteral("12345678901234567890")}, withNumber.Dynamic)
+
+	// non-empty interface isn't supported
+	var stringer fmt.Stringer
+	err := NewDecoder().Unmarshal(Parse([]byte(`"a"`)), &stringer)
+	assert.EqualError(t, err, "cannot unmarshal String into fmt.Stringer: non-empty interface is unsupported")
+
+	// object cannot be set into the other kinds
+	var i int
+	err = NewDecoder().Unmarshal(Parse([]byte(`{}`)), &i)
+	assert.EqualError(t, err, "cannot unmarshal Object into int")
+}
+
+func Test_Unmarshal_DecodeError(t *testing.T) {
+	type (
+		Item struct {
+			Count int8 `json:"count"`
+		}
+		demo struct {
+			Name   string          `json:"name"`
+			Items  []Item          `json:"items"`
+			Flags  map[string]bool `json:"flags"`
+			Ratio  float32         `json:"ratio"`
+			Size   uint            `json:"size"`
+			Tags   []int           `json:"tags"`
+			Nested struct {
+				Done bool `json:"done"`
+			} `json:"nested"`
+		}
+	)
+	testCases := map[string]struct {
+		input    string
+		path     string
+		nodeType NodeType
+		typ      reflect.Type
+		message  string
+	}{
+		"number into string": {input: `{"name": 1}`, path: "/name", nodeType: Number, typ: reflect.TypeOf(""),
+			message: "cannot unmarshal Number into string at /name (line 1, column 10)"},
+		"overflow": {input: `{"items": [{"count": 1}, {"count": 128}]}`, path: "/items/1/count", nodeType: Number, typ: reflect.TypeOf(int8(0)),
+			message: "cannot unmarshal Number into int8 at /items/1/count (line 1, column 36): number 128 overflows"},
+		"fraction into int": {input: `{"items": [{"count": 1.5}]}`, path: "/items/0/count", nodeType: Number, typ: reflect.TypeOf(int8(0)),
+			message: "cannot unmarshal Number into int8 at /items/0/count (line 1, column 22): number 1.5 isn't an integer"},
+		"negative into uint": {input: `{"size": -1}`, path: "/size", nodeType: Number, typ: reflect.TypeOf(uint(0)),
+			message: "cannot unmarshal Number into uint at /size (line 1, column 10): number -1 overflows"},
+		"float32 overflow": {input: `{"ratio": 1e300}`, path: "/ratio", nodeType: Number, typ: reflect.TypeOf(float32(0)),
+			message: "cannot unmarshal Number into float32 at /ratio (line 1, column 11): number 1e+300 overflows"},
+		"string into bool": {input: `{"flags": {"a/b": "yes"}}`, path: "/flags/a~1b", nodeType: String, typ: reflect.TypeOf(false),
+			message: "cannot unmarshal String into bool at /flags/a~1b (line 1, column 19)"},
+		"bool into int": {input: `{"items": [{"count": true}]}`, path: "/items/0/count", nodeType: Bool, typ: reflect.TypeOf(int8(0)),
+			message: "cannot unmarshal Bool into int8 at /items/0/count (line 1, column 22)"},
+		"string into slice": {input: `{"tags": "abc"}`, path: "/tags", nodeType: String, typ: reflect.TypeOf([]int{}),
+			message: "cannot unmarshal String into []int at /tags (line 1, column 10)"},
+		"array into struct": {input: `{"nested": []}`, path: "/nested", nodeType: Array, typ: reflect.TypeOf(demo{}.Nested),
+			message: "cannot unmarshal Array into struct { Done bool \"json:\\\"done\\\"\" } at /nested (line 1, column 12)"},
+		"object into slice": {input: `{"items": {}}`, path: "/items", nodeType: Object, typ: reflect.TypeOf([]Item{}),
+			message: "cannot unmarshal Object into []astjson.Item at /items (line 1, column 11)"},
+		"top-level": {input: `[]`, path: "", nodeType: Array, typ: reflect.TypeOf(demo{}),
+			message: "cannot unmarshal Array into astjson.demo (line 1, column 1)"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var d demo
+			err := NewDecoder().Unmarshal(Parse([]byte(tc.input), WithSpans()), &d)
+
+			var de *DecodeError
+			if assert.ErrorAs(t, err, &de) {
+				assert.Equal(t, tc.path, de.Path)
+				assert.Equal(t, tc.nodeType, de.NodeType)
+				assert.Equal(t, tc.typ, de.Type)
+				assert.NotNil(t, de.Span)
+			}
+			assert.EqualError(t, err, tc.message)
+
+			// the same mismatch is reported by encoding/json
+			assert.Error(t, json.Unmarshal([]byte(tc.input), &d))
+		})
+	}
+
+	// the span is absent without WithSpans
+	var i int
+	err := NewDecoder().Unmarshal(Parse([]byte(`"a"`)), &i)
+	assert.EqualError(t, err, "cannot unmarshal String into int")
+
+	// nil pointer is rejected
+	err = NewDecoder().Unmarshal(Parse([]byte(`1`)), (*int)(nil))
+	assert.EqualError(t, err, "dest must be a non-nil pointer")
+}
+
+func Test_Unmarshal_Pointer(t *testing.T) {
+	type (
+		Sub struct {
+			Name *string `json:"name"`
+			Age  int     `json:"age"`
+		}
+		Base struct {
+			ID *int `json:"id"`
+		}
+		demo struct {
+			*Base
+			Int       *int            `json:"int"`
+			Double    **int           `json:"double"`
+			Sub       *Sub            `json:"sub"`
+			Elems     []*int          `json:"elems"`
+			Values    map[string]*Sub `json:"values"`
+			Slice     *[]int          `json:"slice"`
+			Null      *Sub            `json:"null"`
+			Any       *interface{}    `json:"any"`
+			Untouched *int            `json:"untouched"`
+		}
+	)
+	input := `{"id": 7, "int": 1, "double": 2, "sub": {"name": "a", "age": 3}, "elems": [4, null],
+"values": {"k": {"age": 5}}, "slice": [6], "null": null, "any": {"x": true}}`
+
+	var expected demo
+	assert.NoError(t, json.Unmarshal([]byte(input), &expected))
+	var actual demo
+	assert.NoError(t, NewDecoder().Unmarshal(Parse([]byte(input)), &actual))
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, 7, *actual.ID)
+	assert.Equal(t, 2, **actual.Double)
+	assert.Equal(t, "a", *actual.Sub.Name)
+	assert.Nil(t, actual.Elems[1])
+	assert.Nil(t, actual.Untouched)
+
+	// the existing pointers are kept when decoding into a pre-populated struct
+	name, id := "old", 0
+	populated := demo{Base: &Base{ID: &id}, Sub: &Sub{Name: &name, Age: 10}, Null: &Sub{}}
+	base, sub := populated.Base, populated.Sub
+	assert.NoError(t, NewDecoder().Unmarshal(Parse([]byte(`{"id": 8, "sub": {"name": "new"}, "null": null}`)), &populated))
+	assert.Same(t, base, populated.Base)
+	assert.Same(t, sub, populated.Sub)
+	assert.Same(t, &name, populated.Sub.Name)
+	assert.Equal(t, 8, id)
+	assert.Equal(t, "new", name)
+	assert.Equal(t, 10, populated.Sub.Age)
+	assert.Nil(t, populated.Null)
+
+	// the top-level pointer is followed
+	i := new(int)
+	p := &i
+	assert.NoError(t, NewDecoder().Unmarshal(Parse([]byte(`9`)), p))
+	assert.Equal(t, 9, **p)
+}
+
+func Test_Unmarshal_EmbeddedUnexportedPointer(t *testing.T) {
+	type (
+		inner struct {
+			Name string `json:"name"`
+		}
+		demo struct {
+			*inner
+			Age int `json:"age"`
+		}
+	)
+	var d demo
+	err := NewDecoder().Unmarshal(Parse([]byte(`{"age": 1, "name": "a"}`)), &d)
+	assert.EqualError(t, err, "cannot unmarshal String into astjson.demo at /name: cannot set embedded pointer to unexported struct astjson.inner")
+	assert.Error(t, json.Unmarshal([]byte(`{"age": 1, "name": "a"}`), &d))
+
+	// it's fine if the pointer has been allocated
+	d = demo{inner: &inner{}}
+	assert.NoError(t, NewDecoder().Unmarshal(Parse([]byte(`{"age": 1, "name": "a"}`)), &d))
+	assert.Equal(t, "a", d.Name)
+}
+
+type level int
+
+func (l *level) UnmarshalJSON(bs []byte) error {
+	switch string(bs) {
+	case `"low"`:
+		*l = 1
+	case `"high"`:
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %s", bs)
+	}
+	return nil
+}
+
+// point decodes [x, y] by AstUnmarshaler, and it implements json.Unmarshaler which should be ignored.
+type point struct {
+	X, Y int64
+}
+
+func (p *point) UnmarshalAST(val *Value) error {
+	values := GetArrayValues(val)
+	if len(values) != 2 {
+		return errors.New("point needs 2 coordinates")
+	}
+	p.X, p.Y = GetNumber(&values[0]).GetInt64(), GetNumber(&values[1]).GetInt64()
+	return nil
+}
+
+func (p *point) UnmarshalJSON([]byte) error {
+	return errors.New("UnmarshalJSON shouldn't be called")
+}
+
+func Test_Unmarshal_Unmarshaler(t *testing.T) {
+	type demo struct {
+		Time     time.Time  `json:"time"`
+		TimePtr  *time.Time `json:"time_ptr"`
+		IP       net.IP     `json:"ip"`
+		Level    level      `json:"level"`
+		Levels   []level    `json:"levels"`
+		Point    point      `json:"point"`
+		PointPtr *point     `json:"point_ptr"`
+		Key      upperKey   `json:"key"`
+		Big      *big.Int   `json:"big"`
+		Null     time.Time  `json:"null"`
+	}
+	input := `{"time": "2024-01-02T03:04:05Z", "time_ptr": "2024-01-02T03:04:05Z", "ip": "10.0.0.1",
+"level": "high", "levels": ["low", "high"], "point": [1, 2], "point_ptr": [3, 4], "key": "abc",
+"big": 123456789012345678901234567890, "null": null}`
+
+	var d demo
+	assert.NoError(t, NewDecoder().Unmarshal(Parse([]byte(input), WithNumberLiteral()), &d))
+	expectedTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.True(t, expectedTime.Equal(d.Time))
+	assert.True(t, expectedTime.Equal(*d.TimePtr))
+	assert.Equal(t, "10.0.0.1", d.IP.String())
+	assert.Equal(t, level(2), d.Level)
+	assert.Equal(t, []level{1, 2}, d.Levels)
+	assert.Equal(t, point{X: 1, Y: 2}, d.Point)
+	assert.Equal(t, &point{X: 3, Y: 4}, d.PointPtr)
+	assert.Equal(t, upperKey("ABC"), d.Key)
+	assert.Equal(t, "123456789012345678901234567890", d.Big.String())
+	assert.True(t, d.Null.IsZero())
+
+	errCases := map[string]struct {
+		input   string
+		message string
+	}{
+		"json unmarshaler":     {input: `{"levels": ["low", "mid"]}`, message: `cannot unmarshal String into astjson.level at /levels/1: unknown level "mid"`},
+		"ast unmarshaler":      {input: `{"point": [1]}`, message: `cannot unmarshal Array into astjson.point at /point: point needs 2 coordinates`},
+		"text unmarshaler":     {input: `{"ip": "10.0.0"}`, message: `cannot unmarshal String into net.IP at /ip: invalid IP address: 10.0.0`},
+		"text from non-string": {input: `{"key": 1}`, message: `cannot unmarshal Number into astjson.upperKey at /key`},
+	}
+	for name, tc := range errCases {
+		t.Run(name, func(t *testing.T) {
+			var d demo
+			err := NewDecoder().Unmarshal(Parse([]byte(tc.input)), &d)
+			assert.EqualError(t, err, tc.message)
+
+			var de *DecodeError
+			assert.ErrorAs(t, err, &de)
+		})
+	}
+
+	// the error of unmarshaler is wrapped
+	err := NewDecoder().Unmarshal(Parse([]byte(`{"time": "yesterday"}`)), &d)
+	var pe *time.ParseError
+	assert.ErrorAs(t, err, &pe)
+}
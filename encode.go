@@ -0,0 +1,291 @@
+package astjson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Marshal returns the compact json encoding of val.
+func Marshal(val *Value) ([]byte, error) {
+	var e encodeState
+	if err := e.encode(val); err != nil {
+		return nil, err
+	}
+	return e.bs, nil
+}
+
+// MarshalIndent is like Marshal but each json element begins on a new line
+// with prefix followed by one or more copies of indent according to the nesting.
+func MarshalIndent(val *Value, prefix, indent string) ([]byte, error) {
+	e := encodeState{prefix: prefix, indent: indent}
+	if err := e.encode(val); err != nil {
+		return nil, err
+	}
+	return e.bs, nil
+}
+
+// Encoder writes the json encoding of AST values to an output stream.
+type Encoder struct {
+	w io.Writer
+
+	prefix, indent string
+	sortKeys       bool
+}
+
+// NewEncoder creates an Encoder writes to w, the output is compact by default.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// SetIndent makes the Encoder indent the output like MarshalIndent.
+// Calling SetIndent("", "") disables indentation.
+func (enc *Encoder) SetIndent(prefix, indent string) {
+	enc.prefix, enc.indent = prefix, indent
+}
+
+// SetSortKeys makes the Encoder output object members sorted by keys
+// instead of their original order.
+func (enc *Encoder) SetSortKeys(sortKeys bool) {
+	enc.sortKeys = sortKeys
+}
+
+// Encode writes the json encoding of val followed by a newline character.
+func (enc *Encoder) Encode(val *Value) error {
+	e := encodeState{
+		prefix:   enc.prefix,
+		indent:   enc.indent,
+		sortKeys: enc.sortKeys,
+	}
+	if err := e.encode(val); err != nil {
+		return err
+	}
+	e.bs = append(e.bs, '\n')
+	_, err := enc.w.Write(e.bs)
+	return err
+}
+
+// encodeState accumulates the output of a single value.
+type encodeState struct {
+	bs []byte
+
+	prefix, indent string
+	sortKeys       bool
+	depth          int
+}
+
+func (e *encodeState) encode(val *Value) error {
+	if val == nil {
+		return errors.New("value is a nil pointer")
+	}
+	switch val.NodeType {
+	case Null:
+		e.bs = append(e.bs, "null"...)
+		return nil
+	case Bool:
+		b, ok := val.AstValue.(BoolAst)
+		if !ok {
+			return invalidAstValue(val)
+		}
+		e.bs = strconv.AppendBool(e.bs, bool(b))
+		return nil
+	case Number:
+		n, ok := val.AstValue.(NumberAst)
+		if !ok {
+			return invalidAstValue(val)
+		}
+		return e.number(n)
+	case String:
+		s, ok := val.AstValue.(StringAst)
+		if !ok {
+			return invalidAstValue(val)
+		}
+		e.string(string(s))
+		return nil
+	case Array:
+		ar, ok := val.AstValue.(*ArrayAst)
+		if !ok {
+			return invalidAstValue(val)
+		}
+		return e.array(ar)
+	case Object:
+		obj, ok := val.AstValue.(*ObjectAst)
+		if !ok {
+			return invalidAstValue(val)
+		}
+		return e.object(obj)
+	}
+	return fmt.Errorf("invalid node type %s", val.NodeType)
+}
+
+func invalidAstValue(val *Value) error {
+	return fmt.Errorf("invalid AstValue %T for node type %s", val.AstValue, val.NodeType)
+}
+
+func (e *encodeState) number(n NumberAst) error {
+	if n.literal != "" {
+		// the literal has been verified by lexer
+		e.bs = append(e.bs, n.literal...)
+		return nil
+	}
+	switch n.Nt {
+	case unsignedInteger:
+		e.bs = strconv.AppendUint(e.bs, n.u, 10)
+	case integer:
+		e.bs = strconv.AppendInt(e.bs, n.i, 10)
+	case floatNumber:
+		if math.IsInf(n.f, 0) || math.IsNaN(n.f) {
+			return fmt.Errorf("unsupported number %v", n.f)
+		}
+		// use the shortest representation which could be parsed back to the same float,
+		// and the exponent form only for the very large or small numbers like encoding/json.
+		format := byte('f')
+		if abs := math.Abs(n.f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+			format = 'e'
+		}
+		e.bs = strconv.AppendFloat(e.bs, n.f, format, -1, 64)
+		if format == 'e' {
+			// clean up e-09 to e-9
+			l := len(e.bs)
+			if l >= 4 && e.bs[l-4] == 'e' && e.bs[l-3] == '-' && e.bs[l-2] == '0' {
+				e.bs[l-2] = e.bs[l-1]
+				e.bs = e.bs[:l-1]
+			}
+		}
+	default:
+		return fmt.Errorf("invalid number type %s", n.Nt)
+	}
+	return nil
+}
+
+const hex = "0123456789abcdef"
+
+// string writes s as a quoted json string, the invalid UTF-8 bytes are replaced by U+FFFD.
+func (e *encodeState) string(s string) {
+	e.bs = append(e.bs, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			e.bs = append(e.bs, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				e.bs = append(e.bs, '\\', c)
+			case '\b':
+				e.bs = append(e.bs, '\\', 'b')
+			case '\f':
+				e.bs = append(e.bs, '\\', 'f')
+			case '\n':
+				e.bs = append(e.bs, '\\', 'n')
+			case '\r':
+				e.bs = append(e.bs, '\\', 'r')
+			case '\t':
+				e.bs = append(e.bs, '\\', 't')
+			default:
+				e.bs = append(e.bs, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			e.bs = append(e.bs, s[start:i]...)
+			e.bs = append(e.bs, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid json but break the javascript,
+		// escape them as encoding/json does.
+		if r == '\u2028' || r == '\u2029' {
+			e.bs = append(e.bs, s[start:i]...)
+			e.bs = append(e.bs, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	e.bs = append(e.bs, s[start:]...)
+	e.bs = append(e.bs, '"')
+}
+
+func (e *encodeState) array(ar *ArrayAst) error {
+	if len(ar.Values) == 0 {
+		e.bs = append(e.bs, "[]"...)
+		return nil
+	}
+	e.bs = append(e.bs, '[')
+	e.depth++
+	for i := range ar.Values {
+		if i > 0 {
+			e.bs = append(e.bs, ',')
+		}
+		e.newline()
+		if err := e.encode(&ar.Values[i]); err != nil {
+			return err
+		}
+	}
+	e.depth--
+	e.newline()
+	e.bs = append(e.bs, ']')
+	return nil
+}
+
+func (e *encodeState) object(obj *ObjectAst) error {
+	if obj.Len() == 0 {
+		e.bs = append(e.bs, "{}"...)
+		return nil
+	}
+
+	keys := obj.Keys()
+	if e.sortKeys {
+		sort.Strings(keys)
+	}
+
+	e.bs = append(e.bs, '{')
+	e.depth++
+	for i, key := range keys {
+		if i > 0 {
+			e.bs = append(e.bs, ',')
+		}
+		e.newline()
+		e.string(key)
+		e.bs = append(e.bs, ':')
+		if e.indented() {
+			e.bs = append(e.bs, ' ')
+		}
+		val, _ := obj.Get(key)
+		if err := e.encode(val); err != nil {
+			return err
+		}
+	}
+	e.depth--
+	e.newline()
+	e.bs = append(e.bs, '}')
+	return nil
+}
+
+func (e *encodeState) indented() bool {
+	return e.prefix != "" || e.indent != ""
+}
+
+// newline starts a new line with the prefix and indents for current depth in indent mode.
+func (e *encodeState) newline() {
+	if !e.indented() {
+		return
+	}
+	e.bs = append(e.bs, '\n')
+	e.bs = append(e.bs, e.prefix...)
+	for i := 0; i < e.depth; i++ {
+		e.bs = append(e.bs, e.indent...)
+	}
+}
@@ -0,0 +1,139 @@
+package astjson
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Marshal_Literal(t *testing.T) {
+	a, b := 0.1, 0.2
+	precise := a + b
+	testCases := map[string]struct {
+		input    *Value
+		expected string
+	}{
+		"null":              {input: &Value{NodeType: Null, AstValue: &NullAst{}}, expected: `null`},
+		"true":              {input: &Value{NodeType: Bool, AstValue: BoolAst(true)}, expected: `true`},
+		"false":             {input: &Value{NodeType: Bool, AstValue: BoolAst(false)}, expected: `false`},
+		"max uint64":        {input: &Value{NodeType: Number, AstValue: NumberAst{Nt: unsignedInteger, u: math.MaxUint64}}, expected: `18446744073709551615`},
+		"min int64":         {input: &Value{NodeType: Number, AstValue: NumberAst{Nt: integer, i: math.MinInt64}}, expected: `-9223372036854775808`},
+		"float":             {input: &Value{NodeType: Number, AstValue: NumberAst{Nt: floatNumber, f: -0.99}}, expected: `-0.99`},
+		"integral float":    {input: &Value{NodeType: Number, AstValue: NumberAst{Nt: floatNumber, f: 2e3}}, expected: `2000`},
+		"large float":       {input: &Value{NodeType: Number, AstValue: NumberAst{Nt: floatNumber, f: 1e21}}, expected: `1e+21`},
+		"small float":       {input: &Value{NodeType: Number, AstValue: NumberAst{Nt: floatNumber, f: 1e-7}}, expected: `1e-7`},
+		"precise float":     {input: &Value{NodeType: Number, AstValue: NumberAst{Nt: floatNumber, f: precise}}, expected: `0.30000000000000004`},
+		"string":            {input: &Value{NodeType: String, AstValue: StringAst("hello")}, expected: `"hello"`},
+		"escaped string":    {input: &Value{NodeType: String, AstValue: StringAst("\"\\/\b\f\n\r\t")}, expected: `"\"\\/\b\f\n\r\t"`},
+		"control character": {input: &Value{NodeType: String, AstValue: StringAst("\x00\x1f")}, expected: `"\u0000\u001f"`},
+		"unicode":           {input: &Value{NodeType: String, AstValue: StringAst("你好 <&>")}, expected: `"你好 <&>"`},
+		"line separators":   {input: &Value{NodeType: String, AstValue: StringAst("\u2028\u2029")}, expected: `"\u2028\u2029"`},
+		"invalid utf8":      {input: &Value{NodeType: String, AstValue: StringAst("a\xffb")}, expected: `"a\ufffdb"`},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			bs, err := Marshal(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(bs))
+		})
+	}
+}
+
+func Test_Marshal_RoundTrip(t *testing.T) {
+	testCases := map[string]string{
+		"empty object":  `{}`,
+		"empty array":   `[]`,
+		"nested":        `{"b":[1,-2,3.5,{"c":null}],"a":{"d":[],"e":{}},"f":true}`,
+		"mixed array":   `[1,"a",null,[true],{}]`,
+		"top level str": `"helloworld"`,
+	}
+	for name, input := range testCases {
+		t.Run(name, func(t *testing.T) {
+			bs, err := Marshal(Parse([]byte(input)))
+			assert.NoError(t, err)
+			assert.Equal(t, input, string(bs))
+		})
+	}
+}
+
+func Test_MarshalIndent(t *testing.T) {
+	val := Parse([]byte(`{"b": [1, {}], "a": {"c": []}}`))
+	bs, err := MarshalIndent(val, ">", "  ")
+	assert.NoError(t, err)
+	expected := `{
+>  "b": [
+>    1,
+>    {}
+>  ],
+>  "a": {
+>    "c": []
+>  }
+>}`
+	assert.Equal(t, expected, string(bs))
+}
+
+func Test_Encoder(t *testing.T) {
+	val := Parse([]byte(`{"b": 1, "c": {"z": 1, "y": 2}, "a": 2}`))
+
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	assert.NoError(t, enc.Encode(val))
+	enc.SetSortKeys(true)
+	assert.NoError(t, enc.Encode(val))
+	enc.SetIndent("", "\t")
+	assert.NoError(t, enc.Encode(val))
+
+	expected := `{"b":1,"c":{"z":1,"y":2},"a":2}
+{"a":2,"b":1,"c":{"y":2,"z":1}}
+{
+	"a": 2,
+	"b": 1,
+	"c": {
+		"y": 2,
+		"z": 1
+	}
+}
+`
+	assert.Equal(t, expected, buf.String())
+
+	// sorting keys doesn't change the object
+	assert.Equal(t, []string{"b", "c", "a"}, GetObject(val).Keys())
+}
+
+func Test_Marshal_Error(t *testing.T) {
+	testCases := map[string]*Value{
+		"nil value":          nil,
+		"invalid node type":  {NodeType: 7},
+		"mismatched string":  {NodeType: String, AstValue: BoolAst(true)},
+		"mismatched number":  {NodeType: Number},
+		"NaN":                {NodeType: Number, AstValue: NumberAst{Nt: floatNumber, f: math.NaN()}},
+		"Inf":                {NodeType: Number, AstValue: NumberAst{Nt: floatNumber, f: math.Inf(1)}},
+		"invalid number typ": {NodeType: Number, AstValue: NumberAst{Nt: 5}},
+		"invalid element": {NodeType: Array, AstValue: &ArrayAst{[]Value{
+			{NodeType: Bool, AstValue: StringAst("true")},
+		}}},
+		"invalid member": {NodeType: Object, AstValue: NewObjectAst(Member{Key: "a", Value: Value{NodeType: Object}})},
+	}
+	for name, input := range testCases {
+		t.Run(name, func(t *testing.T) {
+			bs, err := Marshal(input)
+			assert.Error(t, err)
+			assert.Nil(t, bs)
+
+			var buf bytes.Buffer
+			assert.Error(t, NewEncoder(&buf).Encode(input))
+			assert.Zero(t, buf.Len())
+		})
+	}
+}
+
+func Test_Marshal_NumberLiteral(t *testing.T) {
+	input := `[1.50,1e400,-0,18446744073709551617,0.1]`
+	val, err := NewParser([]byte(input), WithNumberLiteral()).ParseWithError()
+	assert.NoError(t, err)
+	bs, err := Marshal(val)
+	assert.NoError(t, err)
+	assert.Equal(t, input, string(bs))
+}
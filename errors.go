@@ -0,0 +1,64 @@
+package astjson
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnexpectedEOF is reported when the input ends before a json value is completed.
+	ErrUnexpectedEOF = errors.New("unexpected end of input")
+	// ErrUnexpectedToken is reported when a token isn't allowed at its place by json syntax.
+	ErrUnexpectedToken = errors.New("unexpected token")
+	// ErrInvalidCharacter is reported when a byte cannot start any json token.
+	ErrInvalidCharacter = errors.New("invalid character")
+	// ErrInvalidString is reported when a string contains an invalid escape sequence.
+	ErrInvalidString = errors.New("invalid string")
+	// ErrInvalidNumber is reported when a number doesn't follow the json number grammar,
+	// or it's out of the range of float64.
+	ErrInvalidNumber = errors.New("invalid number")
+	// ErrInvalidLiteral is reported when a word is neither true, false nor null.
+	ErrInvalidLiteral = errors.New("invalid literal")
+	// ErrDuplicatedKey is reported when an object contains the same key twice.
+	ErrDuplicatedKey = errors.New("duplicated key")
+	// ErrTrailingData is reported when there is data after the top-level value in single document mode.
+	ErrTrailingData = errors.New("trailing data after json value")
+	// ErrInconsistentArray is reported when the elements of an array have different node types.
+	ErrInconsistentArray = errors.New("inconsistent array value type")
+
+	// ErrMaxDepth is reported when arrays and objects are nested deeper than WithMaxDepth.
+	ErrMaxDepth = errors.New("exceeded max nesting depth")
+	// ErrMaxDocumentSize is reported when the input is longer than WithMaxDocumentSize.
+	ErrMaxDocumentSize = errors.New("exceeded max document size")
+	// ErrMaxStringLength is reported when a string or a key is longer than WithMaxStringLength.
+	ErrMaxStringLength = errors.New("exceeded max string length")
+	// ErrMaxObjectKeys is reported when an object has more keys than WithMaxObjectKeys.
+	ErrMaxObjectKeys = errors.New("exceeded max object keys")
+	// ErrMaxArrayLength is reported when an array has more elements than WithMaxArrayLength.
+	ErrMaxArrayLength = errors.New("exceeded max array length")
+)
+
+// ParseError describes why and where the input is not a valid json document.
+// Use errors.Is with the Err* variables to check the kind of failure.
+type ParseError struct {
+	Position
+
+	// Token is the type of the offending token, compare it with the Token* constants
+	Token Type
+	// Expected describes what should occur at Position, it might be empty
+	Expected string
+
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	msg := fmt.Sprintf("%s at line %d, column %d (offset %d)", e.Err, e.Line, e.Column, e.Offset)
+	if e.Expected != "" {
+		msg += fmt.Sprintf(": got %s, expected %s", e.Token, e.Expected)
+	}
+	return msg
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,315 @@
+package jsonpath
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/xieyuschen/astjson"
+)
+
+// filterContext holds the root value and the current value referred by @.
+type filterContext struct {
+	root, current *astjson.Value
+}
+
+// logicalExpr is a filter expression which is evaluated to a boolean.
+type logicalExpr interface {
+	eval(ctx *filterContext) bool
+}
+
+type orExpr []logicalExpr
+
+func (e orExpr) eval(ctx *filterContext) bool {
+	for _, expr := range e {
+		if expr.eval(ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+type andExpr []logicalExpr
+
+func (e andExpr) eval(ctx *filterContext) bool {
+	for _, expr := range e {
+		if !expr.eval(ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+type notExpr struct {
+	expr logicalExpr
+}
+
+func (e notExpr) eval(ctx *filterContext) bool {
+	return !e.expr.eval(ctx)
+}
+
+// existExpr tests whether a query selects at least one node.
+type existExpr struct {
+	query *filterQuery
+}
+
+func (e existExpr) eval(ctx *filterContext) bool {
+	return len(e.query.nodes(ctx)) > 0
+}
+
+// logicalFunc is a function returns a logical type used as a test expression.
+type logicalFunc struct {
+	call *funcCall
+}
+
+func (e logicalFunc) eval(ctx *filterContext) bool {
+	return e.call.logical(ctx)
+}
+
+type compareExpr struct {
+	op          string
+	left, right operand
+}
+
+func (e compareExpr) eval(ctx *filterContext) bool {
+	left, right := e.left.value(ctx), e.right.value(ctx)
+	switch e.op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	case "<":
+		return less(left, right)
+	case "<=":
+		return less(left, right) || equal(left, right)
+	case ">":
+		return less(right, left)
+	case ">=":
+		return less(right, left) || equal(left, right)
+	}
+	return false
+}
+
+// operand is a value inside a comparison or a function argument.
+type operand interface {
+	// value returns the value of operand, nil stands for Nothing
+	value(ctx *filterContext) *astjson.Value
+}
+
+type literal struct {
+	val *astjson.Value
+}
+
+func (l literal) value(*filterContext) *astjson.Value {
+	return l.val
+}
+
+// filterQuery is a query relative to @ or absolute from $ inside the filter.
+type filterQuery struct {
+	relative bool
+	segments []segment
+}
+
+func (q *filterQuery) nodes(ctx *filterContext) []Node {
+	start := ctx.root
+	if q.relative {
+		start = ctx.current
+	}
+	return evalSegments(q.segments, []Node{{Location: "$", Value: start}}, ctx.root)
+}
+
+// singular reports whether the query selects at most one node, which consists of
+// name and index selectors only.
+func (q *filterQuery) singular() bool {
+	for _, seg := range q.segments {
+		if seg.descendant || len(seg.selectors) != 1 {
+			return false
+		}
+		switch seg.selectors[0].(type) {
+		case nameSelector, indexSelector:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (q *filterQuery) value(ctx *filterContext) *astjson.Value {
+	nodes := q.nodes(ctx)
+	if len(nodes) != 1 {
+		return nil
+	}
+	return nodes[0].Value
+}
+
+// resultType is the declared type of function parameters and results.
+type resultType int
+
+const (
+	valueType resultType = iota
+	logicalType
+	nodesType
+)
+
+type funcCall struct {
+	name string
+	args []operand
+
+	// re is the pattern of match() and search() compiled by Compile if it's a literal, it's nil
+	// if the pattern is invalid. The patterns from the queries are compiled lazily and kept
+	// in patterns, which maps a pattern to its *regexp.Regexp.
+	re       *regexp.Regexp
+	literal  bool
+	patterns sync.Map
+	cached   int32
+}
+
+// maxCachedPatterns limits the count of patterns cached by a function call, the patterns
+// from the queries are compiled every time once the limit is reached.
+const maxCachedPatterns = 64
+
+// functions declares the parameter types and the result type of the supported functions.
+var functions = map[string]struct {
+	params []resultType
+	result resultType
+}{
+	"length": {params: []resultType{valueType}, result: valueType},
+	"count":  {params: []resultType{nodesType}, result: valueType},
+	"match":  {params: []resultType{valueType, valueType}, result: logicalType},
+	"search": {params: []resultType{valueType, valueType}, result: logicalType},
+	"value":  {params: []resultType{nodesType}, result: valueType},
+}
+
+func (f *funcCall) value(ctx *filterContext) *astjson.Value {
+	switch f.name {
+	case "length":
+		arg := f.args[0].value(ctx)
+		if arg == nil {
+			return nil
+		}
+		switch arg.NodeType {
+		case astjson.String:
+			return number(utf8.RuneCountInString(astjson.GetString(arg)))
+		case astjson.Array:
+			return number(len(astjson.GetArrayValues(arg)))
+		case astjson.Object:
+			return number(astjson.GetObject(arg).Len())
+		}
+		return nil
+	case "count":
+		return number(len(f.args[0].(*filterQuery).nodes(ctx)))
+	case "value":
+		nodes := f.args[0].(*filterQuery).nodes(ctx)
+		if len(nodes) != 1 {
+			return nil
+		}
+		return nodes[0].Value
+	}
+	return nil
+}
+
+func (f *funcCall) logical(ctx *filterContext) bool {
+	str, pattern := f.args[0].value(ctx), f.args[1].value(ctx)
+	if str == nil || pattern == nil || !astjson.IsString(str) || !astjson.IsString(pattern) {
+		return false
+	}
+	re := f.re
+	if !f.literal {
+		re = f.pattern(astjson.GetString(pattern))
+	}
+	// the invalid pattern matches nothing
+	if re == nil {
+		return false
+	}
+	return re.MatchString(astjson.GetString(str))
+}
+
+// compileLiteral compiles the pattern of match() and search() once if it's a literal.
+func (f *funcCall) compileLiteral() {
+	if l, ok := f.args[1].(literal); ok && astjson.IsString(l.val) {
+		f.re, f.literal = compilePattern(f.name, astjson.GetString(l.val)), true
+	}
+}
+
+// pattern returns the compiled pattern from the cache, or compiles and caches it.
+func (f *funcCall) pattern(pattern string) *regexp.Regexp {
+	if re, ok := f.patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re := compilePattern(f.name, pattern)
+	if atomic.LoadInt32(&f.cached) < maxCachedPatterns && atomic.AddInt32(&f.cached, 1) <= maxCachedPatterns {
+		f.patterns.Store(pattern, re)
+	}
+	return re
+}
+
+// compilePattern compiles the I-Regexp pattern of function name, the match() requires the whole
+// string to match. It returns nil if pattern is invalid.
+func compilePattern(name, pattern string) *regexp.Regexp {
+	expr := iRegexp(pattern)
+	if name == "match" {
+		expr = `^(?:` + expr + `)$`
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// iRegexp translates an I-Regexp(RFC 9485) to the go regexp syntax, the only difference
+// is that '.' outside of character classes doesn't match \n and \r.
+func iRegexp(pattern string) string {
+	var sb strings.Builder
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			sb.WriteByte(c)
+			i++
+			sb.WriteByte(pattern[i])
+			continue
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '.' && !inClass:
+			sb.WriteString(`[^\n\r]`)
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+func number(n int) *astjson.Value {
+	val, _ := astjson.ParseBytes([]byte(strconv.Itoa(n)))
+	return val
+}
+
+// equal compares two values by the json semantic, nil stands for Nothing.
+func equal(a, b *astjson.Value) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return astjson.Equal(a, b)
+}
+
+// less compares numbers and strings only, it returns false for the other types.
+func less(a, b *astjson.Value) bool {
+	if a == nil || b == nil || a.NodeType != b.NodeType {
+		return false
+	}
+	switch a.NodeType {
+	case astjson.Number:
+		return astjson.GetNumber(a).GetFloat64() < astjson.GetNumber(b).GetFloat64()
+	case astjson.String:
+		// comparing the UTF-8 bytes keeps the order of unicode scalar values
+		return astjson.GetString(a) < astjson.GetString(b)
+	}
+	return false
+}
@@ -0,0 +1,280 @@
+// Package jsonpath implements JSONPath(RFC 9535) queries over the astjson AST.
+//
+// A query is compiled once by Compile and could be evaluated against many values:
+//
+//	path := jsonpath.MustCompile(`$.items[?@.price > 10].name`)
+//	for _, node := range path.Query(value) {
+//		fmt.Println(node.Location, node.Value)
+//	}
+//
+// All the selectors are supported, including names, wildcards, indexes, slices and
+// filters with comparisons, logical operators and the functions length, count,
+// match, search and value.
+package jsonpath
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/xieyuschen/astjson"
+)
+
+// ErrSyntax is reported when a query doesn't follow the JSONPath syntax.
+var ErrSyntax = errors.New("invalid jsonpath")
+
+// Node is a value matched by a query alongside its location.
+type Node struct {
+	// Location is the normalized path of Value, for example, $['items'][0]['name']
+	Location string
+	// Value refers to the value inside the queried tree
+	Value *astjson.Value
+}
+
+// Path is a compiled JSONPath query, it's safe for concurrent use.
+type Path struct {
+	expr     string
+	segments []segment
+}
+
+// Compile parses a JSONPath query and returns the Path could be evaluated against values.
+func Compile(expr string) (*Path, error) {
+	p := &parser{expr: expr}
+	segments, err := p.rootQuery()
+	if err != nil {
+		return nil, err
+	}
+	return &Path{expr: expr, segments: segments}, nil
+}
+
+// MustCompile is like Compile but panics if the query cannot be parsed.
+func MustCompile(expr string) *Path {
+	path, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
+
+// Query compiles expr and evaluates it against root.
+func Query(expr string, root *astjson.Value) ([]Node, error) {
+	path, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return path.Query(root), nil
+}
+
+// String returns the original query.
+func (p *Path) String() string {
+	return p.expr
+}
+
+// Query returns all the nodes matched inside root in order, nil is returned if nothing matches.
+func (p *Path) Query(root *astjson.Value) []Node {
+	if root == nil {
+		return nil
+	}
+	return evalSegments(p.segments, []Node{{Location: "$", Value: root}}, root)
+}
+
+// segment is a child segment like [<selectors>] or a descendant segment like ..[<selectors>]
+type segment struct {
+	descendant bool
+	selectors  []selector
+}
+
+// selector selects the children of a node and appends them into out.
+type selector interface {
+	apply(n Node, root *astjson.Value, out []Node) []Node
+}
+
+func evalSegments(segments []segment, nodes []Node, root *astjson.Value) []Node {
+	for _, seg := range segments {
+		var out []Node
+		for _, n := range nodes {
+			if seg.descendant {
+				out = seg.applyDescendant(n, root, out)
+				continue
+			}
+			for _, sel := range seg.selectors {
+				out = sel.apply(n, root, out)
+			}
+		}
+		nodes = out
+	}
+	return nodes
+}
+
+// applyDescendant applies selectors on n and then all the descendants of n in document order.
+func (seg segment) applyDescendant(n Node, root *astjson.Value, out []Node) []Node {
+	for _, sel := range seg.selectors {
+		out = sel.apply(n, root, out)
+	}
+	for _, c := range children(n) {
+		out = seg.applyDescendant(c, root, out)
+	}
+	return out
+}
+
+// children returns the members of an object or the elements of an array in order.
+func children(n Node) []Node {
+	var nodes []Node
+	switch n.Value.NodeType {
+	case astjson.Object:
+		astjson.GetObject(n.Value).Range(func(key string, value *astjson.Value) bool {
+			nodes = append(nodes, Node{Location: n.Location + nameLocation(key), Value: value})
+			return true
+		})
+	case astjson.Array:
+		values := astjson.GetArrayValues(n.Value)
+		for i := range values {
+			nodes = append(nodes, Node{Location: n.Location + indexLocation(i), Value: &values[i]})
+		}
+	}
+	return nodes
+}
+
+type nameSelector string
+
+func (s nameSelector) apply(n Node, _ *astjson.Value, out []Node) []Node {
+	if !astjson.IsObject(n.Value) {
+		return out
+	}
+	if val, ok := astjson.GetObject(n.Value).Get(string(s)); ok {
+		out = append(out, Node{Location: n.Location + nameLocation(string(s)), Value: val})
+	}
+	return out
+}
+
+type wildcardSelector struct{}
+
+func (wildcardSelector) apply(n Node, _ *astjson.Value, out []Node) []Node {
+	return append(out, children(n)...)
+}
+
+type indexSelector int
+
+func (s indexSelector) apply(n Node, _ *astjson.Value, out []Node) []Node {
+	if !astjson.IsArray(n.Value) {
+		return out
+	}
+	values := astjson.GetArrayValues(n.Value)
+	i := int(s)
+	if i < 0 {
+		i += len(values)
+	}
+	if i < 0 || i >= len(values) {
+		return out
+	}
+	return append(out, Node{Location: n.Location + indexLocation(i), Value: &values[i]})
+}
+
+type sliceSelector struct {
+	// start and end are nil if they are omitted
+	start, end *int
+	step       int
+}
+
+func (s sliceSelector) apply(n Node, _ *astjson.Value, out []Node) []Node {
+	if !astjson.IsArray(n.Value) || s.step == 0 {
+		return out
+	}
+	values := astjson.GetArrayValues(n.Value)
+	length := len(values)
+	normalize := func(i int) int {
+		if i < 0 {
+			return length + i
+		}
+		return i
+	}
+	clamp := func(i, lower, upper int) int {
+		if i < lower {
+			return lower
+		}
+		if i > upper {
+			return upper
+		}
+		return i
+	}
+
+	add := func(i int) {
+		out = append(out, Node{Location: n.Location + indexLocation(i), Value: &values[i]})
+	}
+	if s.step > 0 {
+		start, end := 0, length
+		if s.start != nil {
+			start = clamp(normalize(*s.start), 0, length)
+		}
+		if s.end != nil {
+			end = clamp(normalize(*s.end), 0, length)
+		}
+		for i := start; i < end; i += s.step {
+			add(i)
+		}
+		return out
+	}
+
+	start, end := length-1, -1
+	if s.start != nil {
+		start = clamp(normalize(*s.start), -1, length-1)
+	}
+	if s.end != nil {
+		end = clamp(normalize(*s.end), -1, length-1)
+	}
+	for i := start; end < i; i += s.step {
+		add(i)
+	}
+	return out
+}
+
+type filterSelector struct {
+	expr logicalExpr
+}
+
+func (s filterSelector) apply(n Node, root *astjson.Value, out []Node) []Node {
+	for _, c := range children(n) {
+		if s.expr.eval(&filterContext{root: root, current: c.Value}) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func indexLocation(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+// nameLocation formats a member name in the normalized path with the escaping rules.
+func nameLocation(name string) string {
+	var sb strings.Builder
+	sb.WriteString("['")
+	for _, r := range name {
+		switch r {
+		case '\'':
+			sb.WriteString(`\'`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				sb.WriteString(`\u00`)
+				sb.WriteByte("0123456789abcdef"[r>>4])
+				sb.WriteByte("0123456789abcdef"[r&0xF])
+				continue
+			}
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteString("']")
+	return sb.String()
+}
@@ -0,0 +1,324 @@
+package jsonpath
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xieyuschen/astjson"
+)
+
+const bookstore = `{ "store": {
+    "book": [
+      { "category": "reference",
+        "author": "Nigel Rees",
+        "title": "Sayings of the Century",
+        "price": 8.95
+      },
+      { "category": "fiction",
+        "author": "Evelyn Waugh",
+        "title": "Sword of Honour",
+        "price": 12.99
+      },
+      { "category": "fiction",
+        "author": "Herman Melville",
+        "title": "Moby Dick",
+        "isbn": "0-553-21311-3",
+        "price": 8.99
+      },
+      { "category": "fiction",
+        "author": "J. R. R. Tolkien",
+        "title": "The Lord of the Rings",
+        "isbn": "0-395-19395-8",
+        "price": 22.99
+      }
+    ],
+    "bicycle": {
+      "color": "red",
+      "price": 399
+    }
+  }
+}`
+
+// locations returns the normalized paths of nodes.
+func locations(nodes []Node) []string {
+	var paths []string
+	for _, n := range nodes {
+		paths = append(paths, n.Location)
+	}
+	return paths
+}
+
+func Test_Query_Bookstore(t *testing.T) {
+	root := astjson.Parse([]byte(bookstore))
+	testCases := map[string]struct {
+		query    string
+		expected []string
+	}{
+		"authors of all books": {
+			query: `$.store.book[*].author`,
+			expected: []string{
+				`$['store']['book'][0]['author']`, `$['store']['book'][1]['author']`,
+				`$['store']['book'][2]['author']`, `$['store']['book'][3]['author']`,
+			},
+		},
+		"all prices": {
+			query: `$.store..price`,
+			expected: []string{
+				`$['store']['book'][0]['price']`, `$['store']['book'][1]['price']`,
+				`$['store']['book'][2]['price']`, `$['store']['book'][3]['price']`,
+				`$['store']['bicycle']['price']`,
+			},
+		},
+		"third book": {
+			query:    `$..book[2]`,
+			expected: []string{`$['store']['book'][2]`},
+		},
+		"last book": {
+			query:    `$..book[-1]`,
+			expected: []string{`$['store']['book'][3]`},
+		},
+		"first two books by union": {
+			query:    `$..book[0,1]`,
+			expected: []string{`$['store']['book'][0]`, `$['store']['book'][1]`},
+		},
+		"first two books by slice": {
+			query:    `$..book[:2]`,
+			expected: []string{`$['store']['book'][0]`, `$['store']['book'][1]`},
+		},
+		"books with isbn": {
+			query:    `$..book[?@.isbn]`,
+			expected: []string{`$['store']['book'][2]`, `$['store']['book'][3]`},
+		},
+		"books cheaper than 10": {
+			query:    `$..book[?@.price<10]`,
+			expected: []string{`$['store']['book'][0]`, `$['store']['book'][2]`},
+		},
+		"books cheaper than the bicycle and not fiction": {
+			query:    `$.store.book[?@.price < $.store.bicycle.price && !(@.category == 'fiction')]`,
+			expected: []string{`$['store']['book'][0]`},
+		},
+		"bracketed names": {
+			query:    `$["store"]['bicycle'][ 'color' ]`,
+			expected: []string{`$['store']['bicycle']['color']`},
+		},
+		"not found": {
+			query:    `$.store.car`,
+			expected: nil,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			nodes, err := Query(tc.query, root)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, locations(nodes))
+		})
+	}
+}
+
+func Test_Query_Values(t *testing.T) {
+	root := astjson.Parse([]byte(bookstore))
+	nodes := MustCompile(`$.store.book[?@.price > 20].title`).Query(root)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "The Lord of the Rings", astjson.GetString(nodes[0].Value))
+
+	nodes = MustCompile(`$.store.bicycle.price`).Query(root)
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, uint64(399), astjson.GetNumber(nodes[0].Value).GetUint64())
+
+	// the node refers to the value inside the tree
+	nodes[0].Value.AstValue = astjson.GetNumber(astjson.Parse([]byte(`499`)))
+	price, err := root.Pointer("/store/bicycle/price")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(499), astjson.GetNumber(price).GetUint64())
+}
+
+func Test_Query_Slice(t *testing.T) {
+	root := astjson.Parse([]byte(`["a", "b", "c", "d", "e", "f", "g"]`))
+	testCases := map[string]struct {
+		query    string
+		expected []string
+	}{
+		"start and end":  {query: `$[1:3]`, expected: []string{`$[1]`, `$[2]`}},
+		"start only":     {query: `$[5:]`, expected: []string{`$[5]`, `$[6]`}},
+		"step":           {query: `$[1:5:2]`, expected: []string{`$[1]`, `$[3]`}},
+		"negative step":  {query: `$[5:1:-2]`, expected: []string{`$[5]`, `$[3]`}},
+		"reverse":        {query: `$[::-1]`, expected: []string{`$[6]`, `$[5]`, `$[4]`, `$[3]`, `$[2]`, `$[1]`, `$[0]`}},
+		"zero step":      {query: `$[::0]`, expected: nil},
+		"out of range":   {query: `$[-10:2]`, expected: []string{`$[0]`, `$[1]`}},
+		"index too big":  {query: `$[7]`, expected: nil},
+		"negative index": {query: `$[-7]`, expected: []string{`$[0]`}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			nodes, err := Query(tc.query, root)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, locations(nodes))
+		})
+	}
+}
+
+func Test_Query_Filter(t *testing.T) {
+	root := astjson.Parse([]byte(`{
+		"a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}],
+		"o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}},
+		"e": "f"
+	}`))
+	testCases := map[string]struct {
+		query    string
+		expected []string
+	}{
+		"equal string": {
+			query:    `$.a[?@.b == 'kilo']`,
+			expected: []string{`$['a'][9]`},
+		},
+		"greater than number": {
+			query:    `$.a[?@>3.5]`,
+			expected: []string{`$['a'][1]`, `$['a'][4]`, `$['a'][5]`},
+		},
+		"existence": {
+			query:    `$.a[?@.b]`,
+			expected: []string{`$['a'][6]`, `$['a'][7]`, `$['a'][8]`, `$['a'][9]`},
+		},
+		"filter on object": {
+			query:    `$.o[?@<3, ?@<3]`,
+			expected: []string{`$['o']['p']`, `$['o']['q']`, `$['o']['p']`, `$['o']['q']`},
+		},
+		"or": {
+			query:    `$.a[?@<2 || @.b == "k"]`,
+			expected: []string{`$['a'][2]`, `$['a'][7]`},
+		},
+		"compare with absolute query": {
+			query:    `$.a[?@.b == $.x]`,
+			expected: []string{`$['a'][0]`, `$['a'][1]`, `$['a'][2]`, `$['a'][3]`, `$['a'][4]`, `$['a'][5]`},
+		},
+		"compare objects": {
+			query:    `$.a[?@ == $.a[8].b]`,
+			expected: nil,
+		},
+		"compare structured values": {
+			query:    `$.o[?@ == $.o.t]`,
+			expected: []string{`$['o']['t']`},
+		},
+		"length": {
+			query:    `$.a[?length(@.b) == 4]`,
+			expected: []string{`$['a'][9]`},
+		},
+		"count": {
+			query:    `$.o[?count(@.*) == 1]`,
+			expected: []string{`$['o']['t']`},
+		},
+		"match": {
+			query:    `$.a[?match(@.b, "k.*")]`,
+			expected: []string{`$['a'][7]`, `$['a'][9]`},
+		},
+		"search": {
+			query:    `$.a[?search(@.b, "il")]`,
+			expected: []string{`$['a'][9]`},
+		},
+		"value": {
+			query:    `$[?value(@..u) == 6]`,
+			expected: []string{`$['o']`},
+		},
+		"boolean and null literals": {
+			query:    `$.a[?@ == true || @ == null]`,
+			expected: nil,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			nodes, err := Query(tc.query, root)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, locations(nodes))
+		})
+	}
+}
+
+func Test_Query_Pattern(t *testing.T) {
+	call := func(path *Path) *funcCall {
+		return path.segments[0].selectors[0].(filterSelector).expr.(logicalFunc).call
+	}
+	root := astjson.Parse([]byte(`[{"s": "ab", "p": "a."}, {"s": "abc", "p": "b"}, {"s": "x", "p": "("}]`))
+
+	// the literal pattern is compiled once by Compile
+	path := MustCompile(`$[?match(@.s, "a.")]`)
+	assert.NotNil(t, call(path).re)
+	assert.Equal(t, []string{`$[0]`}, locations(path.Query(root)))
+
+	// the invalid pattern matches nothing
+	path = MustCompile(`$[?search(@.s, "(")]`)
+	assert.Nil(t, call(path).re)
+	assert.Nil(t, path.Query(root))
+
+	// the patterns from the queries are compiled once as well
+	path = MustCompile(`$[?search(@.s, @.p)]`)
+	assert.Nil(t, call(path).re)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, []string{`$[0]`, `$[1]`}, locations(path.Query(root)))
+	}
+	assert.Equal(t, int32(3), call(path).cached)
+}
+
+func Test_Query_Descendant(t *testing.T) {
+	root := astjson.Parse([]byte(`{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`))
+	nodes, err := Query(`$..j`, root)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`$['o']['j']`, `$['a'][2][0]['j']`}, locations(nodes))
+
+	nodes, err = Query(`$..[0]`, root)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`$['a'][0]`, `$['a'][2][0]`}, locations(nodes))
+
+	nodes, err = Query(`$..*`, root)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 11)
+}
+
+func Test_Location_Escape(t *testing.T) {
+	root := astjson.Parse([]byte(`{"it's": {"a\\b\n\u0001": 1}}`))
+	nodes, err := Query(`$.*.*`, root)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`$['it\'s']['a\\b\n\u0001']`}, locations(nodes))
+
+	nodes, err = Query(`$['it\'s']["a\\b\n\u0001"]`, root)
+	assert.NoError(t, err)
+	assert.Len(t, nodes, 1)
+}
+
+func Test_Compile_Error(t *testing.T) {
+	testCases := []string{
+		``,
+		`store`,
+		`$ `,
+		`$.`,
+		`$..`,
+		`$.1a`,
+		`$[`,
+		`$[1`,
+		`$[01]`,
+		`$[-0]`,
+		`$[9007199254740992]`,
+		`$['a`,
+		`$['\a']`,
+		`$['\ud83d']`,
+		`$[?@.a == ]`,
+		`$[?1]`,
+		`$[?@.* == 1]`,
+		`$[?@..a == 1]`,
+		`$[?!@.a == 1]`,
+		`$[?length(@.*) == 1]`,
+		`$[?count(1) == 1]`,
+		`$[?count(@.a)]`,
+		`$[?match(@.a)]`,
+		`$[?match(@.a, 'a') == true]`,
+		`$[?foo(@.a)]`,
+		`$[?@.a == 01]`,
+		`$[?@.a == 1.]`,
+		`$[?@.a == nil]`,
+	}
+	for _, query := range testCases {
+		t.Run(query, func(t *testing.T) {
+			_, err := Compile(query)
+			assert.ErrorIs(t, err, ErrSyntax)
+		})
+	}
+}
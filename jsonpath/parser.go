@@ -0,0 +1,586 @@
+package jsonpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/xieyuschen/astjson"
+)
+
+// maxInt is the largest integer allowed in indexes and slices, which is 2^53-1 as I-JSON.
+const maxInt = 1<<53 - 1
+
+// parser parses a JSONPath query, pos is the offset of expr to parse next.
+type parser struct {
+	expr string
+	pos  int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s at offset %d", ErrSyntax, fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.expr)
+}
+
+// peek returns the byte at pos, or 0 at the end of expr.
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.expr[p.pos]
+}
+
+// consume moves pos over s if expr continues with s.
+func (p *parser) consume(s string) bool {
+	if strings.HasPrefix(p.expr[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.consume(s) {
+		return p.errorf("expect %q", s)
+	}
+	return nil
+}
+
+// blank skips the blank characters, leading or trailing blanks of the query aren't allowed.
+func (p *parser) blank() {
+	for !p.eof() {
+		switch p.expr[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// rootQuery parses the whole query which starts with $.
+func (p *parser) rootQuery() ([]segment, error) {
+	if err := p.expect("$"); err != nil {
+		return nil, err
+	}
+	segments, err := p.segments()
+	if err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		return nil, p.errorf("unexpected %q", p.peek())
+	}
+	return segments, nil
+}
+
+// segments parses the segments after $ or @ until no segment follows.
+func (p *parser) segments() ([]segment, error) {
+	var segments []segment
+	for {
+		start := p.pos
+		p.blank()
+		if p.peek() != '.' && p.peek() != '[' {
+			// the blanks belong to what follows the segments
+			p.pos = start
+			return segments, nil
+		}
+		seg, err := p.segment()
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, seg)
+	}
+}
+
+func (p *parser) segment() (segment, error) {
+	var seg segment
+	if p.consume("..") {
+		seg.descendant = true
+		if p.peek() == '[' {
+			return p.bracketed(seg)
+		}
+	} else if !p.consume(".") {
+		return p.bracketed(seg)
+	}
+
+	if p.consume("*") {
+		seg.selectors = []selector{wildcardSelector{}}
+		return seg, nil
+	}
+	name := p.memberName()
+	if name == "" {
+		return seg, p.errorf("expect a member name")
+	}
+	seg.selectors = []selector{nameSelector(name)}
+	return seg, nil
+}
+
+// memberName parses the member name shorthand, it returns empty string if nothing is found.
+func (p *parser) memberName() string {
+	start := p.pos
+	for !p.eof() {
+		r, size := utf8.DecodeRuneInString(p.expr[p.pos:])
+		isFirst := r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') ||
+			(0x80 <= r && r <= 0xD7FF) || (0xE000 <= r && r <= 0x10FFFF && r != utf8.RuneError)
+		if !isFirst && (p.pos == start || r < '0' || r > '9') {
+			break
+		}
+		p.pos += size
+	}
+	return p.expr[start:p.pos]
+}
+
+// bracketed parses the selectors inside [].
+func (p *parser) bracketed(seg segment) (segment, error) {
+	if err := p.expect("["); err != nil {
+		return seg, err
+	}
+	for {
+		p.blank()
+		sel, err := p.selector()
+		if err != nil {
+			return seg, err
+		}
+		seg.selectors = append(seg.selectors, sel)
+		p.blank()
+		if p.consume("]") {
+			return seg, nil
+		}
+		if err := p.expect(","); err != nil {
+			return seg, err
+		}
+	}
+}
+
+func (p *parser) selector() (selector, error) {
+	switch c := p.peek(); c {
+	case '\'', '"':
+		name, err := p.stringLiteral()
+		return nameSelector(name), err
+	case '*':
+		p.pos++
+		return wildcardSelector{}, nil
+	case '?':
+		p.pos++
+		p.blank()
+		expr, err := p.logicalOr()
+		return filterSelector{expr: expr}, err
+	}
+
+	start, err := p.optionalInt()
+	if err != nil {
+		return nil, err
+	}
+	p.blank()
+	if !p.consume(":") {
+		if start == nil {
+			return nil, p.errorf("expect a selector")
+		}
+		return indexSelector(*start), nil
+	}
+
+	s := sliceSelector{start: start, step: 1}
+	p.blank()
+	if s.end, err = p.optionalInt(); err != nil {
+		return nil, err
+	}
+	p.blank()
+	if p.consume(":") {
+		p.blank()
+		step, err := p.optionalInt()
+		if err != nil {
+			return nil, err
+		}
+		if step != nil {
+			s.step = *step
+		}
+	}
+	return s, nil
+}
+
+// optionalInt parses an integer without leading zeros, it returns nil if no integer is found.
+func (p *parser) optionalInt() (*int, error) {
+	start := p.pos
+	p.consume("-")
+	digits := p.pos
+	for !p.eof() && '0' <= p.peek() && p.peek() <= '9' {
+		p.pos++
+	}
+	text := p.expr[start:p.pos]
+	switch {
+	case digits == p.pos && digits == start:
+		return nil, nil
+	case digits == p.pos:
+		return nil, p.errorf("expect digits after -")
+	case p.expr[digits] == '0' && (p.pos-digits > 1 || digits > start):
+		return nil, p.errorf("invalid integer %s", text)
+	}
+	i, err := strconv.Atoi(text)
+	if err != nil || i > maxInt || i < -maxInt {
+		return nil, p.errorf("integer %s out of range", text)
+	}
+	return &i, nil
+}
+
+// stringLiteral parses a string quoted by ' or ".
+func (p *parser) stringLiteral() (string, error) {
+	quote := p.expr[p.pos]
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.expr[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return sb.String(), nil
+		case c < 0x20:
+			return "", p.errorf("control character in string")
+		case c == '\\':
+			r, err := p.escape(quote)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteRune(r)
+		default:
+			r, size := utf8.DecodeRuneInString(p.expr[p.pos:])
+			if r == utf8.RuneError && size == 1 {
+				return "", p.errorf("invalid UTF-8 in string")
+			}
+			sb.WriteString(p.expr[p.pos : p.pos+size])
+			p.pos += size
+		}
+	}
+}
+
+// escape parses an escape sequence inside a string quoted by quote.
+func (p *parser) escape(quote byte) (rune, error) {
+	p.pos++
+	if p.eof() {
+		return 0, p.errorf("unterminated string")
+	}
+	c := p.expr[p.pos]
+	p.pos++
+	switch c {
+	case quote, '/', '\\':
+		return rune(c), nil
+	case 'b':
+		return '\b', nil
+	case 'f':
+		return '\f', nil
+	case 'n':
+		return '\n', nil
+	case 'r':
+		return '\r', nil
+	case 't':
+		return '\t', nil
+	case 'u':
+		r, err := p.hex4()
+		if err != nil {
+			return 0, err
+		}
+		if !utf16.IsSurrogate(r) {
+			return r, nil
+		}
+		// only a high surrogate followed by a low surrogate is allowed
+		if r < 0xDC00 && p.consume(`\u`) {
+			low, err := p.hex4()
+			if err != nil {
+				return 0, err
+			}
+			if dec := utf16.DecodeRune(r, low); dec != utf8.RuneError {
+				return dec, nil
+			}
+		}
+		return 0, p.errorf("invalid surrogate pair")
+	}
+	return 0, p.errorf("invalid escape character %q", c)
+}
+
+func (p *parser) hex4() (rune, error) {
+	if len(p.expr)-p.pos < 4 {
+		return 0, p.errorf("expect 4 hex digits")
+	}
+	u, err := strconv.ParseUint(p.expr[p.pos:p.pos+4], 16, 16)
+	if err != nil {
+		return 0, p.errorf("expect 4 hex digits")
+	}
+	p.pos += 4
+	return rune(u), nil
+}
+
+// logicalOr parses logical-and expressions separated by ||.
+func (p *parser) logicalOr() (logicalExpr, error) {
+	var or orExpr
+	for {
+		expr, err := p.logicalAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, expr)
+		start := p.pos
+		p.blank()
+		if !p.consume("||") {
+			p.pos = start
+			break
+		}
+		p.blank()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+// logicalAnd parses basic expressions separated by &&.
+func (p *parser) logicalAnd() (logicalExpr, error) {
+	var and andExpr
+	for {
+		expr, err := p.basic()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, expr)
+		start := p.pos
+		p.blank()
+		if !p.consume("&&") {
+			p.pos = start
+			break
+		}
+		p.blank()
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+// basic parses a parenthesized expression, a comparison or a test expression.
+func (p *parser) basic() (logicalExpr, error) {
+	not := p.consume("!")
+	if not {
+		p.blank()
+	}
+	if p.consume("(") {
+		p.blank()
+		expr, err := p.logicalOr()
+		if err != nil {
+			return nil, err
+		}
+		p.blank()
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		if not {
+			return notExpr{expr: expr}, nil
+		}
+		return expr, nil
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	start := p.pos
+	p.blank()
+	op := p.comparisonOp()
+	if op == "" {
+		p.pos = start
+		expr, err := p.test(left)
+		if err != nil {
+			return nil, err
+		}
+		if not {
+			return notExpr{expr: expr}, nil
+		}
+		return expr, nil
+	}
+
+	if not {
+		return nil, p.errorf("comparison cannot be negated without parentheses")
+	}
+	if err := p.comparable(left); err != nil {
+		return nil, err
+	}
+	p.blank()
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.comparable(right); err != nil {
+		return nil, err
+	}
+	return compareExpr{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) comparisonOp() string {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consume(op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// test converts the operand to a test expression, which should be a query or a
+// function returns logical type.
+func (p *parser) test(o operand) (logicalExpr, error) {
+	switch o := o.(type) {
+	case *filterQuery:
+		return existExpr{query: o}, nil
+	case *funcCall:
+		if functions[o.name].result == logicalType {
+			return logicalFunc{call: o}, nil
+		}
+		return nil, p.errorf("result of %s() cannot be tested", o.name)
+	}
+	return nil, p.errorf("literal cannot be tested")
+}
+
+// comparable verifies the operand produces a single value.
+func (p *parser) comparable(o operand) error {
+	switch o := o.(type) {
+	case *filterQuery:
+		if !o.singular() {
+			return p.errorf("non-singular query cannot be compared")
+		}
+	case *funcCall:
+		if functions[o.name].result != valueType {
+			return p.errorf("result of %s() cannot be compared", o.name)
+		}
+	}
+	return nil
+}
+
+// operand parses a literal, a query or a function call.
+func (p *parser) operand() (operand, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.pos++
+		segments, err := p.segments()
+		if err != nil {
+			return nil, err
+		}
+		return &filterQuery{relative: c == '@', segments: segments}, nil
+	case c == '\'' || c == '"':
+		s, err := p.stringLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return literal{val: &astjson.Value{NodeType: astjson.String, AstValue: astjson.StringAst(s)}}, nil
+	case c == '-' || ('0' <= c && c <= '9'):
+		return p.number()
+	case 'a' <= c && c <= 'z':
+		start := p.pos
+		for !p.eof() && isFunctionNameChar(p.peek()) {
+			p.pos++
+		}
+		name := p.expr[start:p.pos]
+		if p.peek() == '(' {
+			return p.function(name)
+		}
+		switch name {
+		case "true", "false", "null":
+			val, _ := astjson.ParseBytes([]byte(name))
+			return literal{val: val}, nil
+		}
+		p.pos = start
+		return nil, p.errorf("unknown literal %q", name)
+	}
+	return nil, p.errorf("expect a literal, query or function")
+}
+
+func isFunctionNameChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9')
+}
+
+// number parses a number literal as json number, except that -0 is allowed.
+func (p *parser) number() (operand, error) {
+	start := p.pos
+	digits := func() int {
+		from := p.pos
+		for !p.eof() && '0' <= p.peek() && p.peek() <= '9' {
+			p.pos++
+		}
+		return p.pos - from
+	}
+
+	p.consume("-")
+	intStart := p.pos
+	n := digits()
+	if n == 0 || (n > 1 && p.expr[intStart] == '0') {
+		return nil, p.errorf("invalid number %s", p.expr[start:p.pos])
+	}
+	if p.consume(".") && digits() == 0 {
+		return nil, p.errorf("expect digits after the decimal point")
+	}
+	if p.consume("e") || p.consume("E") {
+		if !p.consume("-") {
+			p.consume("+")
+		}
+		if digits() == 0 {
+			return nil, p.errorf("expect digits in the exponent")
+		}
+	}
+	val, err := astjson.ParseBytes([]byte(p.expr[start:p.pos]))
+	if err != nil {
+		return nil, p.errorf("invalid number %s", p.expr[start:p.pos])
+	}
+	return literal{val: val}, nil
+}
+
+// function parses the arguments of the function name and checks their types.
+func (p *parser) function(name string) (operand, error) {
+	decl, ok := functions[name]
+	if !ok {
+		return nil, p.errorf("unknown function %s", name)
+	}
+	p.pos++
+	call := &funcCall{name: name}
+	p.blank()
+	for !p.consume(")") {
+		if len(call.args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+			p.blank()
+		}
+		arg, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if len(call.args) == len(decl.params) {
+			return nil, p.errorf("too many arguments for %s()", name)
+		}
+		if err := p.argument(decl.params[len(call.args)], arg); err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		p.blank()
+	}
+	if len(call.args) != len(decl.params) {
+		return nil, p.errorf("%s() requires %d arguments", name, len(decl.params))
+	}
+	if name == "match" || name == "search" {
+		call.compileLiteral()
+	}
+	return call, nil
+}
+
+// argument verifies the argument satisfies the declared parameter type.
+func (p *parser) argument(param resultType, arg operand) error {
+	if param == nodesType {
+		if _, ok := arg.(*filterQuery); !ok {
+			return p.errorf("argument should be a query")
+		}
+		return nil
+	}
+	return p.comparable(arg)
+}
@@ -0,0 +1,491 @@
+// Package jsonschema validates astjson values against JSON Schema draft 2020-12.
+//
+// The core, applicator and validation vocabularies are supported. A $ref refers to a subschema
+// inside the same document, either by a json pointer fragment like "#/$defs/name" or by an
+// $anchor like "#name", remote references aren't supported. The format keyword is treated as
+// an annotation only and the unevaluated vocabulary isn't supported. The pattern keywords
+// use the go regular expression syntax(RE2) instead of ECMA-262.
+//
+// A Schema could be used alongside astjson.Walker by Validator:
+//
+//	schema := jsonschema.MustCompile(schemaBytes)
+//	_, err := astjson.NewWalker(value).ValidateKey("payload", schema.Validator()).Walk()
+package jsonschema
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/xieyuschen/astjson"
+)
+
+// ErrInvalidSchema is reported when the schema document isn't a valid JSON Schema.
+var ErrInvalidSchema = errors.New("invalid json schema")
+
+// Keyword compiles the value of a custom keyword in the schema to a Validator, which validates
+// the instances where the keyword applies.
+type Keyword func(value *astjson.Value) (astjson.Validator, error)
+
+// Option customizes the compiling of a Schema.
+type Option func(c *compiler)
+
+// WithKeyword registers a custom keyword, the Validator compiled by keyword is called with the
+// instance and its error is reported as a failure of the keyword.
+func WithKeyword(name string, keyword Keyword) Option {
+	return func(c *compiler) {
+		c.keywords[name] = keyword
+	}
+}
+
+// Schema is a compiled JSON Schema, it's safe for concurrent use as long as the custom
+// keywords are.
+type Schema struct {
+	root *schema
+}
+
+// Compile parses the schema document by astjson.Parser and compiles it.
+// The *astjson.ParseError is returned as it is if the document isn't valid json.
+func Compile(bs []byte, opts ...Option) (*Schema, error) {
+	value, err := astjson.NewParser(bs, astjson.WithSingleDocument()).ParseWithError()
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, fmt.Errorf("%w: empty document", ErrInvalidSchema)
+	}
+	return CompileValue(value, opts...)
+}
+
+// MustCompile is like Compile but panics if the schema cannot be compiled.
+func MustCompile(bs []byte, opts ...Option) *Schema {
+	s, err := Compile(bs, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// CompileValue compiles the schema document which has been parsed, the document shouldn't
+// be changed after compiling.
+func CompileValue(value *astjson.Value, opts ...Option) (*Schema, error) {
+	c := &compiler{
+		root:     value,
+		keywords: map[string]Keyword{},
+		anchors:  map[string]string{},
+		schemas:  map[string]*schema{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if astjson.IsObject(value) {
+		if id, ok := astjson.GetObject(value).Get("$id"); ok && astjson.IsString(id) {
+			c.rootID = strings.TrimSuffix(astjson.GetString(id), "#")
+		}
+	}
+	c.collectAnchors(value, "")
+
+	root, err := c.compile("")
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: root}, nil
+}
+
+// Validate validates value against the schema, it returns ValidationErrors reporting
+// all failures, or nil if the value is valid.
+func (s *Schema) Validate(value *astjson.Value) error {
+	e := &evaluation{}
+	s.root.validate(e, value, "")
+	if len(e.errs) == 0 {
+		return nil
+	}
+	return e.errs
+}
+
+// Validator returns an astjson.Validator validates the value against the schema, so a schema
+// could be applied to any scope of a Walker by Validate, ValidateKey or Optional.
+func (s *Schema) Validator() astjson.Validator {
+	return s.Validate
+}
+
+// ValidationError is a failure of a keyword.
+type ValidationError struct {
+	// InstancePath is the json pointer(RFC 6901) of the invalid value in the validated value
+	InstancePath string
+	// SchemaPath is the json pointer of the failed keyword in the schema document
+	SchemaPath string
+	Err        error
+}
+
+func (e *ValidationError) Error() string {
+	if e.InstancePath == "" {
+		return fmt.Sprintf("%s (schema %s)", e.Err, e.SchemaPath)
+	}
+	return fmt.Sprintf("%s: %s (schema %s)", e.InstancePath, e.Err, e.SchemaPath)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors is all the failures found by Validate.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether any error inside matches target, which helps errors.Is to check
+// the errors inside on every supported go version.
+func (e ValidationErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error inside that matches target, which helps errors.As to check
+// the errors inside on every supported go version.
+func (e ValidationErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// compiler compiles the subschemas of the root document lazily by their locations.
+type compiler struct {
+	root   *astjson.Value
+	rootID string
+	// keywords are the custom keywords
+	keywords map[string]Keyword
+	// anchors maps the $anchor to the location of its schema
+	anchors map[string]string
+	// schemas caches the compiled schemas by location, which breaks the recursive references
+	schemas map[string]*schema
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// join appends the reference token to the json pointer ptr.
+func join(ptr string, token string) string {
+	return ptr + "/" + pointerEscaper.Replace(token)
+}
+
+func (c *compiler) errorf(location string, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s: %s", ErrInvalidSchema, location, fmt.Sprintf(format, args...))
+}
+
+// collectAnchors records all the $anchor and $dynamicAnchor inside value at location.
+func (c *compiler) collectAnchors(value *astjson.Value, location string) {
+	switch value.NodeType {
+	case astjson.Object:
+		astjson.GetObject(value).Range(func(key string, member *astjson.Value) bool {
+			if (key == "$anchor" || key == "$dynamicAnchor") && astjson.IsString(member) {
+				c.anchors[astjson.GetString(member)] = location
+			}
+			c.collectAnchors(member, join(location, key))
+			return true
+		})
+	case astjson.Array:
+		values := astjson.GetArrayValues(value)
+		for i := range values {
+			c.collectAnchors(&values[i], fmt.Sprintf("%s/%d", location, i))
+		}
+	}
+}
+
+// resolve returns the location referred by ref.
+func (c *compiler) resolve(location, ref string) (string, error) {
+	if c.rootID != "" && strings.HasPrefix(ref, c.rootID) {
+		ref = ref[len(c.rootID):]
+	}
+	if ref == "" {
+		return "", nil
+	}
+	if ref[0] != '#' {
+		return "", c.errorf(location, "remote reference %q is not supported", ref)
+	}
+	fragment, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return "", c.errorf(location, "invalid reference %q", ref)
+	}
+	if fragment == "" || fragment[0] == '/' {
+		if _, err := c.root.Pointer(fragment); err != nil {
+			return "", c.errorf(location, "unresolved reference %q: %s", ref, err)
+		}
+		return fragment, nil
+	}
+	target, ok := c.anchors[fragment]
+	if !ok {
+		return "", c.errorf(location, "unresolved reference %q", ref)
+	}
+	return target, nil
+}
+
+// compile compiles the schema at location of the root document.
+func (c *compiler) compile(location string) (*schema, error) {
+	if s, ok := c.schemas[location]; ok {
+		return s, nil
+	}
+	value, err := c.root.Pointer(location)
+	if err != nil {
+		return nil, c.errorf(location, "%s", err)
+	}
+
+	s := &schema{location: location, limits: map[string]int{}}
+	c.schemas[location] = s
+	switch value.NodeType {
+	case astjson.Bool:
+		b := astjson.GetBool(value)
+		s.boolean = &b
+		return s, nil
+	case astjson.Object:
+	default:
+		return nil, c.errorf(location, "schema should be an object or a boolean, got %s", value.NodeType)
+	}
+
+	obj := astjson.GetObject(value)
+	var compileErr error
+	obj.Range(func(key string, member *astjson.Value) bool {
+		compileErr = c.keyword(s, key, member)
+		return compileErr == nil
+	})
+	return s, compileErr
+}
+
+// keyword compiles the keyword key whose value is member into s.
+func (c *compiler) keyword(s *schema, key string, member *astjson.Value) error {
+	location := join(s.location, key)
+	var err error
+	switch key {
+	case "$ref", "$dynamicRef":
+		if !astjson.IsString(member) {
+			return c.errorf(location, "should be a string")
+		}
+		target, err := c.resolve(location, astjson.GetString(member))
+		if err != nil {
+			return err
+		}
+		sub, err := c.compile(target)
+		if err != nil {
+			return err
+		}
+		s.refs = append(s.refs, sub)
+
+	case "type":
+		s.types, err = c.types(location, member)
+	case "enum":
+		if !astjson.IsArray(member) {
+			return c.errorf(location, "should be an array")
+		}
+		values := astjson.GetArrayValues(member)
+		for i := range values {
+			s.enum = append(s.enum, &values[i])
+		}
+	case "const":
+		s.constant = member
+	case "multipleOf":
+		if !astjson.IsNumber(member) || astjson.GetNumber(member).GetFloat64() <= 0 {
+			return c.errorf(location, "should be a number greater than 0")
+		}
+		if s.multipleOf = astjson.GetNumber(member).Rat(); s.multipleOf == nil {
+			return c.errorf(location, "should be a finite number")
+		}
+	case "maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum":
+		if !astjson.IsNumber(member) {
+			return c.errorf(location, "should be a number")
+		}
+		f := astjson.GetNumber(member).GetFloat64()
+		s.bounds = append(s.bounds, bound{keyword: key, limit: f})
+	case "maxLength", "minLength", "maxItems", "minItems", "maxContains", "minContains",
+		"maxProperties", "minProperties":
+		n, err := c.nonNegative(location, member)
+		if err != nil {
+			return err
+		}
+		s.limits[key] = n
+	case "pattern":
+		s.pattern, err = c.regexp(location, member)
+	case "uniqueItems":
+		if !astjson.IsBool(member) {
+			return c.errorf(location, "should be a boolean")
+		}
+		s.uniqueItems = astjson.GetBool(member)
+	case "required":
+		s.required, err = c.strings(location, member)
+	case "dependentRequired":
+		if !astjson.IsObject(member) {
+			return c.errorf(location, "should be an object")
+		}
+		astjson.GetObject(member).Range(func(name string, value *astjson.Value) bool {
+			var required []string
+			required, err = c.strings(join(location, name), value)
+			s.dependentRequired = append(s.dependentRequired, dependency{property: name, required: required})
+			return err == nil
+		})
+
+	case "allOf", "anyOf", "oneOf", "prefixItems":
+		var schemas []*schema
+		if schemas, err = c.schemaArray(location, member); err != nil {
+			return err
+		}
+		switch key {
+		case "allOf":
+			s.allOf = schemas
+		case "anyOf":
+			s.anyOf = schemas
+		case "oneOf":
+			s.oneOf = schemas
+		case "prefixItems":
+			s.prefixItems = schemas
+		}
+	case "not", "if", "then", "else", "items", "contains", "additionalProperties", "propertyNames":
+		var sub *schema
+		if sub, err = c.compile(location); err != nil {
+			return err
+		}
+		switch key {
+		case "not":
+			s.not = sub
+		case "if":
+			s.ifSchema = sub
+		case "then":
+			s.thenSchema = sub
+		case "else":
+			s.elseSchema = sub
+		case "items":
+			s.items = sub
+		case "contains":
+			s.contains = sub
+		case "additionalProperties":
+			s.additionalProperties = sub
+		case "propertyNames":
+			s.propertyNames = sub
+		}
+	case "properties", "patternProperties", "dependentSchemas":
+		if !astjson.IsObject(member) {
+			return c.errorf(location, "should be an object")
+		}
+		astjson.GetObject(member).Range(func(name string, _ *astjson.Value) bool {
+			var sub *schema
+			if sub, err = c.compile(join(location, name)); err != nil {
+				return false
+			}
+			switch key {
+			case "properties":
+				s.properties = append(s.properties, namedSchema{name: name, schema: sub})
+			case "dependentSchemas":
+				s.dependentSchemas = append(s.dependentSchemas, namedSchema{name: name, schema: sub})
+			case "patternProperties":
+				var re *regexp.Regexp
+				if re, err = regexp.Compile(name); err != nil {
+					err = c.errorf(join(location, name), "invalid pattern: %s", err)
+					return false
+				}
+				s.patternProperties = append(s.patternProperties, patternSchema{pattern: re, schema: sub})
+			}
+			return true
+		})
+
+	default:
+		keyword, ok := c.keywords[key]
+		if !ok {
+			// the unknown keywords and the annotations are ignored
+			return nil
+		}
+		validator, err := keyword(member)
+		if err != nil {
+			return c.errorf(location, "%s", err)
+		}
+		s.custom = append(s.custom, customKeyword{keyword: key, validator: validator})
+	}
+	return err
+}
+
+// typeNames are the valid values of the type keyword.
+var typeNames = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "string": true, "integer": true,
+}
+
+func (c *compiler) types(location string, value *astjson.Value) ([]string, error) {
+	var types []string
+	if astjson.IsString(value) {
+		types = []string{astjson.GetString(value)}
+	} else {
+		var err error
+		if types, err = c.strings(location, value); err != nil {
+			return nil, c.errorf(location, "should be a string or an array of strings")
+		}
+	}
+	for _, tp := range types {
+		if !typeNames[tp] {
+			return nil, c.errorf(location, "unknown type %q", tp)
+		}
+	}
+	return types, nil
+}
+
+// strings reads an array of strings.
+func (c *compiler) strings(location string, value *astjson.Value) ([]string, error) {
+	if !astjson.IsArray(value) {
+		return nil, c.errorf(location, "should be an array of strings")
+	}
+	values := astjson.GetArrayValues(value)
+	strs := make([]string, 0, len(values))
+	for i := range values {
+		if !astjson.IsString(&values[i]) {
+			return nil, c.errorf(location, "should be an array of strings")
+		}
+		strs = append(strs, astjson.GetString(&values[i]))
+	}
+	return strs, nil
+}
+
+// schemaArray compiles the non-empty array of schemas at location.
+func (c *compiler) schemaArray(location string, value *astjson.Value) ([]*schema, error) {
+	if !astjson.IsArray(value) || len(astjson.GetArrayValues(value)) == 0 {
+		return nil, c.errorf(location, "should be a non-empty array of schemas")
+	}
+	var schemas []*schema
+	for i := range astjson.GetArrayValues(value) {
+		sub, err := c.compile(fmt.Sprintf("%s/%d", location, i))
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, sub)
+	}
+	return schemas, nil
+}
+
+func (c *compiler) nonNegative(location string, value *astjson.Value) (int, error) {
+	if astjson.IsNumber(value) {
+		f := astjson.GetNumber(value).GetFloat64()
+		if f >= 0 && f == float64(int(f)) {
+			return int(f), nil
+		}
+	}
+	return 0, c.errorf(location, "should be a non-negative integer")
+}
+
+func (c *compiler) regexp(location string, value *astjson.Value) (*regexp.Regexp, error) {
+	if !astjson.IsString(value) {
+		return nil, c.errorf(location, "should be a string")
+	}
+	re, err := regexp.Compile(astjson.GetString(value))
+	if err != nil {
+		return nil, c.errorf(location, "invalid pattern: %s", err)
+	}
+	return re, nil
+}
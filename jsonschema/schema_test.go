@@ -0,0 +1,331 @@
+package jsonschema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xieyuschen/astjson"
+)
+
+// failures returns the instance paths and the schema paths of err.
+func failures(t *testing.T, err error) [][2]string {
+	if err == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	if !assert.True(t, errors.As(err, &errs)) {
+		return nil
+	}
+	var paths [][2]string
+	for _, e := range errs {
+		paths = append(paths, [2]string{e.InstancePath, e.SchemaPath})
+	}
+	return paths
+}
+
+func Test_Validate(t *testing.T) {
+	testCases := map[string]struct {
+		schema   string
+		instance string
+		expected [][2]string
+	}{
+		"true schema":  {schema: `true`, instance: `1`},
+		"false schema": {schema: `false`, instance: `1`, expected: [][2]string{{"", ""}}},
+		"type": {
+			schema:   `{"type": "integer"}`,
+			instance: `1.5`,
+			expected: [][2]string{{"", "/type"}},
+		},
+		"integer with zero fraction": {schema: `{"type": "integer"}`, instance: `1.0`},
+		"integer is a number":        {schema: `{"type": ["number", "null"]}`, instance: `1`},
+		"enum": {
+			schema:   `{"enum": ["a", 1, {"b": [null]}]}`,
+			instance: `{"b": [false]}`,
+			expected: [][2]string{{"", "/enum"}},
+		},
+		"enum matches":     {schema: `{"enum": ["a", 1, {"b": [null]}]}`, instance: `1.0`},
+		"const":            {schema: `{"const": [1, "a"]}`, instance: `[1, "a"]`},
+		"multipleOf":       {schema: `{"multipleOf": 0.1}`, instance: `0.3`},
+		"not multipleOf":   {schema: `{"multipleOf": 2}`, instance: `7`, expected: [][2]string{{"", "/multipleOf"}}},
+		"inclusive bounds": {schema: `{"minimum": 1, "maximum": 3}`, instance: `3`},
+		"exclusive bounds": {
+			schema:   `{"exclusiveMinimum": 1, "exclusiveMaximum": 3}`,
+			instance: `3`,
+			expected: [][2]string{{"", "/exclusiveMaximum"}},
+		},
+		"string length counts code points": {schema: `{"maxLength": 2, "minLength": 2}`, instance: `"你好"`},
+		"pattern": {
+			schema:   `{"pattern": "^a+$", "minLength": 2}`,
+			instance: `"b"`,
+			expected: [][2]string{{"", "/minLength"}, {"", "/pattern"}},
+		},
+		"keywords for other types are ignored": {schema: `{"minLength": 2, "required": ["a"]}`, instance: `1`},
+		"object": {
+			schema: `{
+				"type": "object",
+				"properties": {"name": {"type": "string"}, "age": {"type": "integer", "minimum": 0}},
+				"patternProperties": {"^x-": {"type": "string"}},
+				"additionalProperties": false,
+				"required": ["name", "email"]
+			}`,
+			instance: `{"name": 1, "age": -1, "x-a": "b", "x-b": 1, "other": true}`,
+			expected: [][2]string{
+				{"", "/required"},
+				{"/name", "/properties/name/type"},
+				{"/age", "/properties/age/minimum"},
+				{"/x-b", "/patternProperties/^x-/type"},
+				{"/other", "/additionalProperties"},
+			},
+		},
+		"object size and names": {
+			schema:   `{"maxProperties": 1, "propertyNames": {"maxLength": 1}}`,
+			instance: `{"a": 1, "bc": 2}`,
+			expected: [][2]string{{"", "/maxProperties"}, {"/bc", "/propertyNames/maxLength"}},
+		},
+		"dependencies": {
+			schema: `{
+				"dependentRequired": {"card": ["billing"]},
+				"dependentSchemas": {"vip": {"required": ["level"]}}
+			}`,
+			instance: `{"card": 1, "vip": true}`,
+			expected: [][2]string{{"", "/dependentRequired"}, {"", "/dependentSchemas/vip/required"}},
+		},
+		"array": {
+			schema: `{
+				"prefixItems": [{"type": "string"}],
+				"items": {"type": "integer"},
+				"minItems": 2,
+				"uniqueItems": true
+			}`,
+			instance: `["a", 1, 1.5, 1]`,
+			expected: [][2]string{{"", "/uniqueItems"}, {"/2", "/items/type"}},
+		},
+		"contains": {
+			schema:   `{"contains": {"type": "string"}}`,
+			instance: `[1, 2]`,
+			expected: [][2]string{{"", "/contains"}},
+		},
+		"min and max contains": {
+			schema:   `{"contains": {"type": "string"}, "minContains": 2, "maxContains": 3}`,
+			instance: `["a", 1]`,
+			expected: [][2]string{{"", "/minContains"}},
+		},
+		"zero minContains": {schema: `{"contains": {"type": "string"}, "minContains": 0}`, instance: `[]`},
+		"combinators": {
+			schema: `{
+				"allOf": [{"minimum": 1}, {"maximum": 5}],
+				"anyOf": [{"type": "integer"}, {"type": "string"}],
+				"oneOf": [{"multipleOf": 2}, {"multipleOf": 3}],
+				"not": {"const": 7}
+			}`,
+			instance: `6.5`,
+			expected: [][2]string{{"", "/allOf/1/maximum"}, {"", "/anyOf"}, {"", "/oneOf"}},
+		},
+		"conditional": {
+			schema: `{
+				"if": {"properties": {"kind": {"const": "a"}}},
+				"then": {"required": ["a"]},
+				"else": {"required": ["b"]}
+			}`,
+			instance: `{"kind": "b"}`,
+			expected: [][2]string{{"", "/else/required"}},
+		},
+		"local ref": {
+			schema: `{
+				"$defs": {"positive": {"type": "integer", "exclusiveMinimum": 0}},
+				"properties": {"count": {"$ref": "#/$defs/positive"}}
+			}`,
+			instance: `{"count": 0}`,
+			expected: [][2]string{{"/count", "/$defs/positive/exclusiveMinimum"}},
+		},
+		"anchor ref": {
+			schema: `{
+				"$id": "https://example.com/schema",
+				"$defs": {"name": {"$anchor": "name", "type": "string"}},
+				"items": {"$ref": "https://example.com/schema#name"}
+			}`,
+			instance: `["a", 1]`,
+			expected: [][2]string{{"/1", "/$defs/name/type"}},
+		},
+		"recursive ref": {
+			schema: `{
+				"type": "object",
+				"properties": {"children": {"type": "array", "items": {"$ref": "#"}}},
+				"required": ["name"]
+			}`,
+			instance: `{"name": "a", "children": [{"name": "b", "children": [{}]}]}`,
+			expected: [][2]string{{"/children/0/children/0", "/required"}},
+		},
+		"escaped pointer ref": {
+			schema: `{
+				"$defs": {"a/b": {"type": "null"}, "c%d": {"type": "null"}},
+				"prefixItems": [{"$ref": "#/$defs/a~1b"}, {"$ref": "#/$defs/c%25d"}]
+			}`,
+			instance: `[null, 1]`,
+			expected: [][2]string{{"/1", "/$defs/c%d/type"}},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s, err := Compile([]byte(tc.schema))
+			if !assert.NoError(t, err) {
+				return
+			}
+			err = s.Validate(astjson.Parse([]byte(tc.instance)))
+			assert.Equal(t, tc.expected, failures(t, err))
+		})
+	}
+}
+
+func Test_Compile_Error(t *testing.T) {
+	testCases := map[string]string{
+		"not a schema":        `1`,
+		"invalid type":        `{"type": "int"}`,
+		"invalid type array":  `{"type": ["string", 1]}`,
+		"negative minLength":  `{"minLength": -1}`,
+		"fraction maxItems":   `{"maxItems": 1.5}`,
+		"zero multipleOf":     `{"multipleOf": 0}`,
+		"invalid pattern":     `{"pattern": "("}`,
+		"invalid sub schema":  `{"properties": {"a": 1}}`,
+		"empty allOf":         `{"allOf": []}`,
+		"unresolved pointer":  `{"$ref": "#/$defs/a"}`,
+		"unresolved anchor":   `{"$ref": "#a"}`,
+		"remote reference":    `{"$ref": "https://example.com/schema"}`,
+		"invalid required":    `{"required": "a"}`,
+		"invalid dependency":  `{"dependentRequired": {"a": [1]}}`,
+		"invalid enum":        `{"enum": 1}`,
+		"invalid ref target":  `{"$defs": {"a": 1}, "$ref": "#/$defs/a"}`,
+		"invalid nested item": `{"items": {"not": "a"}}`,
+	}
+	for name, schema := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Compile([]byte(schema))
+			assert.ErrorIs(t, err, ErrInvalidSchema)
+		})
+	}
+
+	_, err := Compile([]byte(`{"type": }`))
+	var parseErr *astjson.ParseError
+	assert.True(t, errors.As(err, &parseErr))
+
+	_, err = Compile([]byte(`{} {}`))
+	assert.ErrorIs(t, err, astjson.ErrTrailingData)
+}
+
+func Test_ValidationError(t *testing.T) {
+	s := MustCompile([]byte(`{"properties": {"a": {"type": "string"}}, "required": ["b"]}`))
+	err := s.Validate(astjson.Parse([]byte(`{"a": 1}`)))
+	assert.EqualError(t, err, `property "b" is required (schema /required); /a: expected string, got integer (schema /properties/a/type)`)
+
+	// the self referring schema is stopped
+	s = MustCompile([]byte(`{"$ref": "#"}`))
+	assert.Error(t, s.Validate(astjson.Parse([]byte(`1`))))
+}
+
+func Test_Validate_MultipleOf_Precision(t *testing.T) {
+	testCases := map[string]struct {
+		schema   string
+		instance string
+		message  string
+	}{
+		"beyond float64":      {schema: `{"multipleOf": 2}`, instance: `1e400`},
+		"not multiple beyond": {schema: `{"multipleOf": 3}`, instance: `1e400`, message: "1e400 should be a multiple of 3 (schema /multipleOf)"},
+		"huge exponent":       {schema: `{"multipleOf": 2}`, instance: `1e99999`, message: "1e99999 cannot be represented exactly (schema /multipleOf)"},
+		"big odd integer":     {schema: `{"multipleOf": 2}`, instance: `12345678901234567891`, message: "12345678901234567891 should be a multiple of 2 (schema /multipleOf)"},
+		"big even integer":    {schema: `{"multipleOf": 2}`, instance: `12345678901234567890`},
+		"big negative":        {schema: `{"multipleOf": 10}`, instance: `-123456789012345678901234567890`},
+		"decimal literal":     {schema: `{"multipleOf": 0.01}`, instance: `19.99`},
+		"big decimal":         {schema: `{"multipleOf": 0.01}`, instance: `12345678901234567.891`, message: "12345678901234567.891 should be a multiple of 1/100 (schema /multipleOf)"},
+		"integral big float":  {schema: `{"multipleOf": 3}`, instance: `3e30`},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := MustCompile([]byte(tc.schema))
+			err := s.Validate(astjson.Parse([]byte(tc.instance), astjson.WithNumberLiteral()))
+			if tc.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.message)
+		})
+	}
+
+	// the big integer is exact without the literal text as well
+	s := MustCompile([]byte(`{"multipleOf": 2}`))
+	assert.Error(t, s.Validate(astjson.Parse([]byte(`18446744073709551615`))))
+
+	// the multipleOf cannot be represented is rejected
+	_, err := CompileValue(astjson.Parse([]byte(`{"multipleOf": 1e99999}`), astjson.WithNumberLiteral()))
+	assert.ErrorIs(t, err, ErrInvalidSchema)
+}
+
+func Test_CustomKeyword(t *testing.T) {
+	errOdd := errors.New("value should be even")
+	even := func(value *astjson.Value) (astjson.Validator, error) {
+		if !astjson.IsBool(value) {
+			return nil, errors.New("should be a boolean")
+		}
+		if !astjson.GetBool(value) {
+			return func(*astjson.Value) error { return nil }, nil
+		}
+		return func(value *astjson.Value) error {
+			if astjson.IsNumber(value) && astjson.GetNumber(value).GetInt64()%2 != 0 {
+				return errOdd
+			}
+			return nil
+		}, nil
+	}
+
+	s, err := Compile([]byte(`{"items": {"even": true}}`), WithKeyword("even", even))
+	assert.NoError(t, err)
+	err = s.Validate(astjson.Parse([]byte(`[2, 3, 4]`)))
+	var errs ValidationErrors
+	if assert.True(t, errors.As(err, &errs)) {
+		assert.ErrorIs(t, errs[0], errOdd)
+		// the errors inside are checked by the Is and As methods without relying on go 1.20
+		assert.True(t, errs.Is(errOdd))
+		var validationErr *ValidationError
+		assert.True(t, errs.As(&validationErr))
+		assert.Equal(t, "/1", validationErr.InstancePath)
+	}
+	assert.Equal(t, [][2]string{{"/1", "/items/even"}}, failures(t, err))
+
+	_, err = Compile([]byte(`{"even": 1}`), WithKeyword("even", even))
+	assert.ErrorIs(t, err, ErrInvalidSchema)
+
+	// the keyword is ignored without registering
+	s = MustCompile([]byte(`{"items": {"even": true}}`))
+	assert.NoError(t, s.Validate(astjson.Parse([]byte(`[3]`))))
+}
+
+func Test_Schema_Walker(t *testing.T) {
+	s := MustCompile([]byte(`{"type": "array", "items": {"type": "object", "required": ["id"]}}`))
+	plan := astjson.NewWalker(nil).Field("name").ValidateKey("items", s.Validator())
+
+	_, err := plan.WalkValue(astjson.Parse([]byte(`{"name": "a", "items": [{"id": 1}, {"id": 2}]}`)))
+	assert.NoError(t, err)
+
+	_, err = plan.WalkValue(astjson.Parse([]byte(`{"name": "a", "items": [{"id": 1}, {}]}`)))
+	// the instance path of the schema failure is relative to the value walked by the validator
+	assert.EqualError(t, err, `/items: /1: property "id" is required (schema /items/required)`)
+
+	// the schema validates the object of a path scope and the top-level object
+	object := MustCompile([]byte(`{"type": "object", "required": ["id"]}`))
+	doc := astjson.Parse([]byte(`{"a": {"id": 1}, "b": {}}`))
+	_, err = astjson.NewWalker(doc).Path("a").Validate(object.Validator()).Walk()
+	assert.NoError(t, err)
+	_, err = astjson.NewWalker(doc).Path("b").Validate(object.Validator()).Walk()
+	assert.EqualError(t, err, `/b: property "id" is required (schema /required)`)
+	_, err = astjson.NewWalker(doc).Validate(object.Validator()).Walk()
+	assert.EqualError(t, err, `property "id" is required (schema /required)`)
+
+	// the failure is located by the path scope in the aggregate mode
+	_, err = astjson.NewWalker(doc).Aggregate().
+		Path("a").Validate(object.Validator()).EndPath().
+		Path("b").Validate(object.Validator()).Walk()
+	var errs astjson.WalkErrors
+	if assert.ErrorAs(t, err, &errs) && assert.Len(t, errs, 1) {
+		assert.Equal(t, "/b", errs[0].Path)
+	}
+}
@@ -0,0 +1,384 @@
+package jsonschema
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xieyuschen/astjson"
+)
+
+// maxDepth limits the nested evaluations, which stops the schema referring itself endlessly.
+const maxDepth = 1000
+
+// schema is a compiled schema at location of the schema document.
+type schema struct {
+	location string
+	// boolean is set for the boolean schema true and false
+	boolean *bool
+
+	// refs are the targets of $ref and $dynamicRef
+	refs []*schema
+
+	// validation vocabulary
+	types       []string
+	enum        []*astjson.Value
+	constant    *astjson.Value
+	multipleOf  *big.Rat
+	bounds      []bound
+	pattern     *regexp.Regexp
+	uniqueItems bool
+	// limits are the keywords limit the length, size or count like maxLength
+	limits            map[string]int
+	required          []string
+	dependentRequired []dependency
+
+	// applicator vocabulary
+	allOf, anyOf, oneOf              []*schema
+	not                              *schema
+	ifSchema, thenSchema, elseSchema *schema
+	dependentSchemas                 []namedSchema
+	prefixItems                      []*schema
+	items                            *schema
+	contains                         *schema
+	properties                       []namedSchema
+	patternProperties                []patternSchema
+	additionalProperties             *schema
+	propertyNames                    *schema
+
+	custom []customKeyword
+}
+
+type bound struct {
+	keyword string
+	limit   float64
+}
+
+type dependency struct {
+	property string
+	required []string
+}
+
+type namedSchema struct {
+	name   string
+	schema *schema
+}
+
+type patternSchema struct {
+	pattern *regexp.Regexp
+	schema  *schema
+}
+
+type customKeyword struct {
+	keyword   string
+	validator astjson.Validator
+}
+
+// evaluation collects the failures of validating an instance.
+type evaluation struct {
+	errs  ValidationErrors
+	depth int
+}
+
+func (s *schema) fail(e *evaluation, instancePath, keyword string, err error) {
+	schemaPath := s.location
+	if keyword != "" {
+		schemaPath = join(s.location, keyword)
+	}
+	e.errs = append(e.errs, &ValidationError{InstancePath: instancePath, SchemaPath: schemaPath, Err: err})
+}
+
+func (s *schema) failf(e *evaluation, instancePath, keyword, format string, args ...interface{}) {
+	s.fail(e, instancePath, keyword, fmt.Errorf(format, args...))
+}
+
+// valid reports whether value is valid against s without reporting the failures.
+func (s *schema) valid(e *evaluation, value *astjson.Value, instancePath string) bool {
+	sub := &evaluation{depth: e.depth}
+	s.validate(sub, value, instancePath)
+	return len(sub.errs) == 0
+}
+
+// validate validates value at instancePath and reports the failures to e.
+func (s *schema) validate(e *evaluation, value *astjson.Value, instancePath string) {
+	if s.boolean != nil {
+		if !*s.boolean {
+			s.failf(e, instancePath, "", "false schema allows nothing")
+		}
+		return
+	}
+	if e.depth++; e.depth > maxDepth {
+		s.failf(e, instancePath, "", "maximum depth %d of evaluation exceeded", maxDepth)
+		return
+	}
+	defer func() { e.depth-- }()
+
+	for _, r := range s.refs {
+		r.validate(e, value, instancePath)
+	}
+	s.validateAny(e, value, instancePath)
+	switch value.NodeType {
+	case astjson.Number:
+		s.validateNumber(e, astjson.GetNumber(value), instancePath)
+	case astjson.String:
+		s.validateString(e, astjson.GetString(value), instancePath)
+	case astjson.Array:
+		s.validateArray(e, astjson.GetArrayValues(value), instancePath)
+	case astjson.Object:
+		s.validateObject(e, astjson.GetObject(value), instancePath)
+	}
+	for _, c := range s.custom {
+		if err := c.validator(value); err != nil {
+			s.fail(e, instancePath, c.keyword, err)
+		}
+	}
+}
+
+// validateAny validates the keywords apply to any types.
+func (s *schema) validateAny(e *evaluation, value *astjson.Value, instancePath string) {
+	if len(s.types) > 0 && !matchType(s.types, value) {
+		s.failf(e, instancePath, "type", "expected %s, got %s", strings.Join(s.types, " or "), typeName(value))
+	}
+	if s.enum != nil {
+		found := false
+		for _, v := range s.enum {
+			if astjson.Equal(v, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			s.failf(e, instancePath, "enum", "value should be one of the enum values")
+		}
+	}
+	if s.constant != nil && !astjson.Equal(s.constant, value) {
+		s.failf(e, instancePath, "const", "value should be equal to the const value")
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(e, value, instancePath)
+	}
+	if s.anyOf != nil {
+		matched := false
+		for _, sub := range s.anyOf {
+			if sub.valid(e, value, instancePath) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			s.failf(e, instancePath, "anyOf", "value should match at least one schema")
+		}
+	}
+	if s.oneOf != nil {
+		matched := 0
+		for _, sub := range s.oneOf {
+			if sub.valid(e, value, instancePath) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			s.failf(e, instancePath, "oneOf", "value should match exactly one schema, matched %d", matched)
+		}
+	}
+	if s.not != nil && s.not.valid(e, value, instancePath) {
+		s.failf(e, instancePath, "not", "value shouldn't match the schema")
+	}
+	if s.ifSchema != nil {
+		if s.ifSchema.valid(e, value, instancePath) {
+			if s.thenSchema != nil {
+				s.thenSchema.validate(e, value, instancePath)
+			}
+		} else if s.elseSchema != nil {
+			s.elseSchema.validate(e, value, instancePath)
+		}
+	}
+}
+
+func (s *schema) validateNumber(e *evaluation, n astjson.NumberAst, instancePath string) {
+	f := n.GetFloat64()
+	if s.multipleOf != nil {
+		// the exact value is taken from the literal text or the integer, so 0.1 is 1/10 and
+		// the integers beyond float64 precision are kept
+		r := n.Rat()
+		if r == nil {
+			s.failf(e, instancePath, "multipleOf", "%s cannot be represented exactly", n)
+		} else if !new(big.Rat).Quo(r, s.multipleOf).IsInt() {
+			s.failf(e, instancePath, "multipleOf", "%s should be a multiple of %s", n, s.multipleOf.RatString())
+		}
+	}
+	for _, b := range s.bounds {
+		var ok bool
+		var op string
+		switch b.keyword {
+		case "maximum":
+			ok, op = f <= b.limit, "<="
+		case "exclusiveMaximum":
+			ok, op = f < b.limit, "<"
+		case "minimum":
+			ok, op = f >= b.limit, ">="
+		case "exclusiveMinimum":
+			ok, op = f > b.limit, ">"
+		}
+		if !ok {
+			s.failf(e, instancePath, b.keyword, "%v should be %s %v", f, op, b.limit)
+		}
+	}
+}
+
+func (s *schema) validateString(e *evaluation, str string, instancePath string) {
+	length := utf8.RuneCountInString(str)
+	if limit, ok := s.limits["maxLength"]; ok && length > limit {
+		s.failf(e, instancePath, "maxLength", "length %d should be <= %d", length, limit)
+	}
+	if limit, ok := s.limits["minLength"]; ok && length < limit {
+		s.failf(e, instancePath, "minLength", "length %d should be >= %d", length, limit)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		s.failf(e, instancePath, "pattern", "%q should match pattern %q", str, s.pattern)
+	}
+}
+
+func (s *schema) validateArray(e *evaluation, values []astjson.Value, instancePath string) {
+	if limit, ok := s.limits["maxItems"]; ok && len(values) > limit {
+		s.failf(e, instancePath, "maxItems", "%d items should be <= %d", len(values), limit)
+	}
+	if limit, ok := s.limits["minItems"]; ok && len(values) < limit {
+		s.failf(e, instancePath, "minItems", "%d items should be >= %d", len(values), limit)
+	}
+	if s.uniqueItems {
+	Loop:
+		for i := range values {
+			for j := i + 1; j < len(values); j++ {
+				if astjson.Equal(&values[i], &values[j]) {
+					s.failf(e, instancePath, "uniqueItems", "items %d and %d should be unique", i, j)
+					break Loop
+				}
+			}
+		}
+	}
+
+	for i := range values {
+		itemPath := instancePath + "/" + strconv.Itoa(i)
+		if i < len(s.prefixItems) {
+			s.prefixItems[i].validate(e, &values[i], itemPath)
+		} else if s.items != nil {
+			s.items.validate(e, &values[i], itemPath)
+		}
+	}
+
+	if s.contains != nil {
+		matched := 0
+		for i := range values {
+			if s.contains.valid(e, &values[i], instancePath+"/"+strconv.Itoa(i)) {
+				matched++
+			}
+		}
+		minContains, ok := s.limits["minContains"]
+		if !ok {
+			minContains = 1
+		}
+		if matched < minContains {
+			keyword := "contains"
+			if ok {
+				keyword = "minContains"
+			}
+			s.failf(e, instancePath, keyword, "%d items match contains, should be >= %d", matched, minContains)
+		}
+		if limit, ok := s.limits["maxContains"]; ok && matched > limit {
+			s.failf(e, instancePath, "maxContains", "%d items match contains, should be <= %d", matched, limit)
+		}
+	}
+}
+
+func (s *schema) validateObject(e *evaluation, obj *astjson.ObjectAst, instancePath string) {
+	if limit, ok := s.limits["maxProperties"]; ok && obj.Len() > limit {
+		s.failf(e, instancePath, "maxProperties", "%d properties should be <= %d", obj.Len(), limit)
+	}
+	if limit, ok := s.limits["minProperties"]; ok && obj.Len() < limit {
+		s.failf(e, instancePath, "minProperties", "%d properties should be >= %d", obj.Len(), limit)
+	}
+	for _, name := range s.required {
+		if _, ok := obj.Get(name); !ok {
+			s.failf(e, instancePath, "required", "property %q is required", name)
+		}
+	}
+	for _, dep := range s.dependentRequired {
+		if _, ok := obj.Get(dep.property); !ok {
+			continue
+		}
+		for _, name := range dep.required {
+			if _, ok := obj.Get(name); !ok {
+				s.failf(e, instancePath, "dependentRequired", "property %q is required by %q", name, dep.property)
+			}
+		}
+	}
+	for _, dep := range s.dependentSchemas {
+		if _, ok := obj.Get(dep.name); ok {
+			dep.schema.validate(e, &astjson.Value{NodeType: astjson.Object, AstValue: obj}, instancePath)
+		}
+	}
+
+	obj.Range(func(key string, value *astjson.Value) bool {
+		propertyPath := join(instancePath, key)
+		if s.propertyNames != nil {
+			name := &astjson.Value{NodeType: astjson.String, AstValue: astjson.StringAst(key)}
+			s.propertyNames.validate(e, name, propertyPath)
+		}
+
+		evaluated := false
+		for _, p := range s.properties {
+			if p.name == key {
+				evaluated = true
+				p.schema.validate(e, value, propertyPath)
+			}
+		}
+		for _, p := range s.patternProperties {
+			if p.pattern.MatchString(key) {
+				evaluated = true
+				p.schema.validate(e, value, propertyPath)
+			}
+		}
+		if !evaluated && s.additionalProperties != nil {
+			s.additionalProperties.validate(e, value, propertyPath)
+		}
+		return true
+	})
+}
+
+func matchType(types []string, value *astjson.Value) bool {
+	actual := typeName(value)
+	for _, tp := range types {
+		if tp == actual || (tp == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeName returns the json schema type of value, the number without fraction is an integer.
+func typeName(value *astjson.Value) string {
+	switch value.NodeType {
+	case astjson.Null:
+		return "null"
+	case astjson.Bool:
+		return "boolean"
+	case astjson.String:
+		return "string"
+	case astjson.Array:
+		return "array"
+	case astjson.Object:
+		return "object"
+	case astjson.Number:
+		f := astjson.GetNumber(value).GetFloat64()
+		if f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "unknown"
+}
@@ -2,27 +2,32 @@ package astjson
 
 import (
 	"fmt"
+	"io"
 	"strconv"
+	"unicode/utf8"
 )
 
-// Type represents the token type
+// Type represents the token type, its String describes the token in the error messages.
 //
-//go:generate stringer -type=Type
+//go:generate stringer -type=Type -linecomment
 type Type uint
 
+// The token types reported by ParseError.Token.
 const (
-	tkWhiteSpace Type = iota
-	tkString
-	tkNumber
-	tkBool
-	tkNull
-	tkEOF
-	tkObjectStart
-	tkObjectEnd
-	tkArrayStart
-	tkArrayEnd
-	tkComma
-	tkColon
+	TokenWhiteSpace  Type = iota // whitespace
+	TokenString                  // string
+	TokenNumber                  // number
+	TokenBool                    // boolean
+	TokenNull                    // null
+	TokenEOF                     // end of input
+	TokenObjectStart             // '{'
+	TokenObjectEnd               // '}'
+	TokenArrayStart              // '['
+	TokenArrayEnd                // ']'
+	TokenComma                   // ','
+	TokenColon                   // ':'
+	// TokenInvalid is the input which cannot be any token
+	TokenInvalid // invalid token
 )
 
 // token represents the json token.
@@ -35,76 +40,208 @@ type token struct {
 	// index starts at 0
 	leftPos, rightPos int
 
-	// hasDash and isFloat only make sense for tkNumber because we don't
+	// hasDash and isFloat only make sense for TokenNumber because we don't
 	// want to lose precise
 	hasDash, isFloat bool
 }
 
 type lexer struct {
-	bs []byte
+	// bs holds the input from offset base, positions inside lexer are offsets of the whole
+	// input, so they need to subtract base before indexing bs.
+	bs   []byte
+	base int
+
+	// r is the source of input, it's nil when the whole input is given by bs
+	r io.Reader
+	// eof reports r has no more data, readErr is the non io.EOF error returned by r
+	eof     bool
+	readErr error
+
+	// maxSize limits the length of input if it's positive, and tooLarge reports the input
+	// is longer than it
+	maxSize  int
+	tooLarge bool
 
 	// todo: try to use uint
 	curPos  int
 	lastPos int
+
+	// line is the count of '\n' scanned so far and lineStart is the offset next to the last one,
+	// tokenLine and tokenLineStart are the ones at the start of the last token. They help to
+	// compute the line and column of a position without keeping the offsets of all '\n'.
+	line, lineStart           int
+	tokenLine, tokenLineStart int
 }
 
+// defaultBufferSize is the initial buffer size for a lexer reading from io.Reader.
+const defaultBufferSize = 4096
+
 func newLexer(bs []byte) *lexer {
 	return &lexer{
 		bs:      bs,
 		curPos:  0,
 		lastPos: 0,
+		eof:     true,
+	}
+}
+
+// newReaderLexer creates a lexer which reads input from r on demand. Only the bytes
+// from the start of the token being scanned are kept in memory.
+func newReaderLexer(r io.Reader) *lexer {
+	return &lexer{
+		bs: make([]byte, 0, defaultBufferSize),
+		r:  r,
 	}
 }
 
+// Reset rewinds the lexer to the beginning of input.
+// It does nothing for a lexer reading from io.Reader because the scanned input is discarded.
 func (l *lexer) Reset() {
+	if l.r != nil {
+		return
+	}
 	l.curPos, l.lastPos = 0, 0
+	l.line, l.lineStart = 0, 0
+	l.tokenLine, l.tokenLineStart = 0, 0
+}
+
+// fill ensures at least n bytes are available from curPos, reading more input if necessary.
+// It reports false if the input ends before, or the input exceeds maxSize.
+func (l *lexer) fill(n int) bool {
+	if l.tooLarge || l.exceeded() {
+		return false
+	}
+	for len(l.bs)-(l.curPos-l.base) < n {
+		if l.eof {
+			return false
+		}
+		// discard the bytes before the current token as nobody refers them anymore
+		if discard := l.lastPos - l.base; discard > 0 {
+			l.bs = l.bs[:copy(l.bs, l.bs[discard:])]
+			l.base = l.lastPos
+		}
+		if len(l.bs) == cap(l.bs) {
+			bs := make([]byte, len(l.bs), 2*cap(l.bs)+n)
+			copy(bs, l.bs)
+			l.bs = bs
+		}
+		buf := l.bs[len(l.bs):cap(l.bs)]
+		// one more byte than the limit is enough to know the input is too large
+		if rest := l.maxSize - l.base - len(l.bs) + 1; l.maxSize > 0 && rest < len(buf) {
+			buf = buf[:rest]
+		}
+		read, err := l.r.Read(buf)
+		l.bs = l.bs[:len(l.bs)+read]
+		if err != nil {
+			l.eof = true
+			if err != io.EOF {
+				l.readErr = err
+			}
+		}
+		if l.exceeded() {
+			return false
+		}
+	}
+	return true
+}
+
+// exceeded reports whether the input read so far is longer than maxSize, all bytes of input
+// are counted including the ones haven't been scanned yet.
+func (l *lexer) exceeded() bool {
+	if l.maxSize > 0 && l.base+len(l.bs) > l.maxSize {
+		l.tooLarge = true
+	}
+	return l.tooLarge
+}
+
+// peek returns the byte at curPos+i, it reports false if the input ends before.
+func (l *lexer) peek(i int) (byte, bool) {
+	if !l.fill(i + 1) {
+		return 0, false
+	}
+	return l.bs[l.curPos-l.base+i], true
+}
+
+// text returns the bytes of the token tk which is the last scanned one.
+func (l *lexer) text(tk token) []byte {
+	return l.bs[tk.leftPos-l.base : tk.rightPos-l.base]
+}
+
+// position returns the Position of offset. The offset should be inside the last scanned
+// token or after it, so the Position of an earlier token needs to be computed before
+// scanning further.
+func (l *lexer) position(offset int) Position {
+	line, lineStart := l.line, l.lineStart
+	// the offset is before the newlines inside the last token, a string might contain them
+	if offset < lineStart {
+		line, lineStart = l.tokenLine, l.tokenLineStart
+	}
+	return Position{
+		Offset: offset,
+		Line:   line + 1,
+		Column: offset - lineStart + 1,
+	}
 }
 
-// Scan returns one token or panic
-// todo: return error instead of panic
-func (l *lexer) Scan() token {
+// newline records the '\n' at curPos.
+func (l *lexer) newline() {
+	l.line++
+	l.lineStart = l.curPos + 1
+}
+
+// errorf constructs a ParseError happens at offset while scanning a tp token.
+func (l *lexer) errorf(offset int, tp Type, expected string, err error) *ParseError {
+	return &ParseError{
+		Position: l.position(offset),
+		Token:    tp,
+		Expected: expected,
+		Err:      err,
+	}
+}
+
+// Scan returns one token or an error when the input is invalid.
+// The error returned by the underlying io.Reader is reported as it is.
+func (l *lexer) Scan() (token, error) {
+	tk, err := l.scan()
+	if l.readErr != nil {
+		return token{}, l.readErr
+	}
+	if l.tooLarge {
+		// the input before the limit may not be scanned yet, count its newlines for
+		// the position of error
+		for ; l.curPos < l.maxSize; l.curPos++ {
+			if l.bs[l.curPos-l.base] == '\n' {
+				l.newline()
+			}
+		}
+		return token{}, l.errorf(l.maxSize, TokenEOF, "", ErrMaxDocumentSize)
+	}
+	return tk, err
+}
+
+func (l *lexer) scan() (token, error) {
 	// align sentries
 	l.lastPos = l.curPos
+	l.tokenLine, l.tokenLineStart = l.line, l.lineStart
 
-	if l.curPos == len(l.bs) {
+	c, ok := l.peek(0)
+	if !ok {
 		return token{
-			tp:       tkEOF,
+			tp:       TokenEOF,
 			leftPos:  l.curPos,
 			rightPos: l.curPos,
-		}
+		}, nil
 	}
 
-	c := l.bs[l.curPos]
-
 	switch c {
 	case '{':
-		l.curPos += 1
-		return token{
-			tp:       tkObjectStart,
-			leftPos:  l.lastPos,
-			rightPos: l.curPos,
-		}
+		return l.single(TokenObjectStart), nil
 	case '}':
-		l.curPos += 1
-		return token{
-			tp:       tkObjectEnd,
-			leftPos:  l.lastPos,
-			rightPos: l.curPos,
-		}
+		return l.single(TokenObjectEnd), nil
 	case '[':
-		l.curPos += 1
-		return token{
-			tp:       tkArrayStart,
-			leftPos:  l.lastPos,
-			rightPos: l.curPos,
-		}
+		return l.single(TokenArrayStart), nil
 	case ']':
-		l.curPos += 1
-		return token{
-			tp:       tkArrayEnd,
-			leftPos:  l.lastPos,
-			rightPos: l.curPos,
-		}
+		return l.single(TokenArrayEnd), nil
 	case '"':
 		// string case
 		return l.stringType()
@@ -114,135 +251,197 @@ func (l *lexer) Scan() token {
 	case 'n':
 		// null case
 		return l.nullType()
-	case ' ', '\t', '\n', '\r':
-		l.curPos += 1
-		return token{
-			tp:       tkWhiteSpace,
-			leftPos:  l.lastPos,
-			rightPos: l.curPos,
-		}
+	case '\n':
+		l.newline()
+		return l.single(TokenWhiteSpace), nil
+	case ' ', '\t', '\r':
+		return l.single(TokenWhiteSpace), nil
 	case ':':
-		l.curPos += 1
-		return token{
-			tp:       tkColon,
-			leftPos:  l.lastPos,
-			rightPos: l.curPos,
-		}
+		return l.single(TokenColon), nil
 	case ',':
-		l.curPos += 1
-		return token{
-			tp:       tkComma,
-			leftPos:  l.lastPos,
-			rightPos: l.curPos,
-		}
-	default:
+		return l.single(TokenComma), nil
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 		// number case
 		return l.numberType()
+	default:
+		return token{}, l.errorf(l.curPos, TokenInvalid, "", fmt.Errorf("%w %s", ErrInvalidCharacter, quoteChar(c)))
+	}
+}
+
+// quoteChar quotes the byte c for the error messages, the non-ASCII byte is shown in hex
+// because it's only a part of a character.
+func quoteChar(c byte) string {
+	if c < utf8.RuneSelf {
+		return strconv.QuoteRune(rune(c))
+	}
+	return fmt.Sprintf("0x%02x", c)
+}
+
+// single returns a tp token which occupies one byte.
+func (l *lexer) single(tp Type) token {
+	l.curPos += 1
+	return token{
+		tp:       tp,
+		leftPos:  l.lastPos,
+		rightPos: l.curPos,
 	}
 }
 
-func (l *lexer) stringType() token {
+func (l *lexer) stringType() (token, error) {
 	// move next to the starting "
 	l.curPos++
 
-	for l.curPos < len(l.bs) {
-		if l.bs[l.curPos] == '\\' {
+	for {
+		c, ok := l.peek(0)
+		if !ok {
+			break
+		}
+		switch c {
+		case '\\':
 			l.curPos++
-			switch l.bs[l.curPos] {
+			e, ok := l.peek(0)
+			if !ok {
+				break
+			}
+			switch e {
 			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
 				l.curPos++
-				continue
 			case 'u':
 				// u1234: check whether it's a hex digital
-				s := l.bs[l.curPos+1 : l.curPos+5]
-				_, err := strconv.ParseUint(string(s), 16, 64)
-				if err != nil {
-					panic(fmt.Errorf("invalid hex string at %d", l.curPos))
+				if !l.fill(5) {
+					l.curPos += len(l.bs) - (l.curPos - l.base)
+					break
+				}
+				if _, ok := hex4(l.bs[l.curPos-l.base+1:]); !ok {
+					return token{}, l.errorf(l.curPos+1, TokenString, "4 hex digits", ErrInvalidString)
 				}
 				l.curPos += 5
 			default:
-				panic(fmt.Sprintf("invalid string \\ near %d", l.curPos))
+				return token{}, l.errorf(l.curPos, TokenString, "an escape character", ErrInvalidString)
 			}
-		}
-		if l.bs[l.curPos] != '"' {
+		case '"':
+			// move curPos right because we need to conclude " as wel
+			l.curPos++
+			return token{
+				tp:      TokenString,
+				leftPos: l.lastPos,
+				// the curPos ends at where the second " occurs
+				rightPos: l.curPos,
+			}, nil
+		case '\n':
+			l.newline()
+			l.curPos++
+		default:
 			l.curPos++
-			continue
-		}
-
-		// move curPos right because we need to conclude " as wel
-		l.curPos++
-		return token{
-			tp:      tkString,
-			leftPos: l.lastPos,
-			// the curPos ends at where the second " occurs
-			rightPos: l.curPos,
 		}
 	}
-	panic(fmt.Sprintf("invalid string from %d to %d", l.lastPos, l.curPos))
+	return token{}, l.errorf(l.curPos, TokenEOF, `closing "`, ErrUnexpectedEOF)
 }
 
-// todo: return error instead of panic
-func (l *lexer) boolType() token {
-	if string(l.bs[l.lastPos:l.curPos+len("true")]) == "true" {
-		l.curPos += len("true")
-		return token{
-			tp:       tkBool,
-			leftPos:  l.lastPos,
-			rightPos: l.curPos,
-		}
-	}
-
-	if string(l.bs[l.lastPos:l.curPos+len("false")]) == "false" {
-		l.curPos += len("false")
-		return token{
-			tp:       tkBool,
-			leftPos:  l.lastPos,
-			rightPos: l.curPos,
-		}
+func (l *lexer) boolType() (token, error) {
+	if c, _ := l.peek(0); c == 't' {
+		return l.word("true", TokenBool, "true or false")
 	}
-
-	panic("not a valid json bool type")
+	return l.word("false", TokenBool, "true or false")
 }
 
-// todo: return error instead of panic
-func (l *lexer) nullType() token {
-	l.curPos += 4
-	str := string(l.bs[l.lastPos:l.curPos])
+func (l *lexer) nullType() (token, error) {
+	return l.word("null", TokenNull, "null")
+}
 
-	if str == "null" {
-		return token{
-			tp:       tkNull,
-			leftPos:  l.lastPos,
-			rightPos: l.curPos,
+// word scans the literal w as a tp token. The input ends in the middle of w is reported
+// as ErrUnexpectedEOF at the end of input, and the other mismatches are reported as an
+// invalid token at the start of the literal.
+func (l *lexer) word(w string, tp Type, expected string) (token, error) {
+	for i := 0; i < len(w); i++ {
+		c, ok := l.peek(i)
+		if !ok {
+			return token{}, l.errorf(l.curPos+i, TokenEOF, expected, ErrUnexpectedEOF)
+		}
+		if c != w[i] {
+			return token{}, l.errorf(l.curPos, TokenInvalid, expected, ErrInvalidLiteral)
 		}
 	}
-
-	panic("not a valid null value")
+	l.curPos += len(w)
+	return token{
+		tp:       tp,
+		leftPos:  l.lastPos,
+		rightPos: l.curPos,
+	}, nil
 }
 
-func (l *lexer) numberType() token {
+// numberType scans a number by the json number grammar:
+//
+//	number = [ "-" ] ( "0" / digit1-9 *DIGIT ) [ "." 1*DIGIT ] [ ( "e" / "E" ) [ "+" / "-" ] 1*DIGIT ]
+func (l *lexer) numberType() (token, error) {
 	t := token{
-		tp:      tkNumber,
+		tp:      TokenNumber,
 		leftPos: l.lastPos,
 	}
-	switch l.bs[l.curPos] {
-	case '-':
+	if c, _ := l.peek(0); c == '-' {
 		t.hasDash = true
-		fallthrough
-	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 		l.curPos++
-	Loop:
-		for ; l.curPos < len(l.bs); l.curPos++ {
-			switch l.bs[l.curPos] {
-			case '.', 'e', 'E':
-				t.isFloat = true
-			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '+', '-':
-			default:
-				break Loop
-			}
+	}
+
+	c, ok := l.peek(0)
+	switch {
+	case c == '0':
+		l.curPos++
+	case '1' <= c && c <= '9':
+		l.digits()
+	default:
+		return token{}, l.numberError(ok)
+	}
+
+	if c, _ := l.peek(0); c == '.' {
+		t.isFloat = true
+		l.curPos++
+		if c, ok := l.peek(0); !isDigit(c) {
+			return token{}, l.numberError(ok)
 		}
+		l.digits()
+	}
+
+	if c, _ := l.peek(0); c == 'e' || c == 'E' {
+		t.isFloat = true
+		l.curPos++
+		if c, _ := l.peek(0); c == '+' || c == '-' {
+			l.curPos++
+		}
+		if c, ok := l.peek(0); !isDigit(c) {
+			return token{}, l.numberError(ok)
+		}
+		l.digits()
+	}
+
+	// a number must not be followed by the characters could continue it, like 01 or 1.2.3
+	if c, ok := l.peek(0); ok && (isDigit(c) || c == '.' || c == 'e' || c == 'E' || c == '+' || c == '-') {
+		return token{}, l.errorf(l.curPos, TokenNumber, "end of number", ErrInvalidNumber)
 	}
 
 	t.rightPos = l.curPos
-	return t
+	return t, nil
+}
+
+// digits moves curPos over the consecutive digits.
+func (l *lexer) digits() {
+	for {
+		c, ok := l.peek(0)
+		if !ok || !isDigit(c) {
+			return
+		}
+		l.curPos++
+	}
+}
+
+// numberError reports a digit is required at curPos, ok is false if the input ends there.
+func (l *lexer) numberError(ok bool) *ParseError {
+	if !ok {
+		return l.errorf(l.curPos, TokenEOF, "a digit", ErrUnexpectedEOF)
+	}
+	return l.errorf(l.curPos, TokenNumber, "a digit", ErrInvalidNumber)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
 }
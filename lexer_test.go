@@ -12,170 +12,170 @@ func Test_Scan_Simple_Tokens(t *testing.T) {
 		expected token
 	}{
 		"eof": {input: ``, expected: token{
-			tp:       tkEOF,
+			tp:       TokenEOF,
 			leftPos:  0,
 			rightPos: 0,
 		}},
 		"string": {input: `"123"`, expected: token{
-			tp:       tkString,
+			tp:       TokenString,
 			leftPos:  0,
 			rightPos: 5,
 		}},
 		"string with backward slash": {input: `"\"123"`, expected: token{
-			tp:       tkString,
+			tp:       TokenString,
 			leftPos:  0,
 			rightPos: 7,
 		}},
 		`string with \"`: {input: `"\"123"`, expected: token{
-			tp:       tkString,
+			tp:       TokenString,
 			leftPos:  0,
 			rightPos: 7,
 		}},
 		`string with \\"`: {input: `"\\123"`, expected: token{
-			tp:       tkString,
+			tp:       TokenString,
 			leftPos:  0,
 			rightPos: 7,
 		}},
 		`string with \/"`: {input: `"\/123"`, expected: token{
-			tp:       tkString,
+			tp:       TokenString,
 			leftPos:  0,
 			rightPos: 7,
 		}},
 		`string with \b"`: {input: `"\b123"`, expected: token{
-			tp:       tkString,
+			tp:       TokenString,
 			leftPos:  0,
 			rightPos: 7,
 		}},
 		`string with \f"`: {input: `"\f123"`, expected: token{
-			tp:       tkString,
+			tp:       TokenString,
 			leftPos:  0,
 			rightPos: 7,
 		}},
 		`string with \n"`: {input: `"\n123"`, expected: token{
-			tp:       tkString,
+			tp:       TokenString,
 			leftPos:  0,
 			rightPos: 7,
 		}},
 		`string with \r"`: {input: `"\r123"`, expected: token{
-			tp:       tkString,
+			tp:       TokenString,
 			leftPos:  0,
 			rightPos: 7,
 		}},
 		`string with \t"`: {input: `"\t123"`, expected: token{
-			tp:       tkString,
+			tp:       TokenString,
 			leftPos:  0,
 			rightPos: 7,
 		}},
 		`string with \u1234"`: {input: `"\u1234"`, expected: token{
-			tp:       tkString,
+			tp:       TokenString,
 			leftPos:  0,
 			rightPos: 8,
 		}},
 		`string with \uabcd"`: {input: `"\uabcd"`, expected: token{
-			tp:       tkString,
+			tp:       TokenString,
 			leftPos:  0,
 			rightPos: 8,
 		}},
 		`string with \uffff"`: {input: `"\uffff"`, expected: token{
-			tp:       tkString,
+			tp:       TokenString,
 			leftPos:  0,
 			rightPos: 8,
 		}},
 		"positive integer": {input: "999", expected: token{
-			tp:       tkNumber,
+			tp:       TokenNumber,
 			leftPos:  0,
 			rightPos: 3,
 		}},
 		"negative integer": {input: "-999", expected: token{
-			tp:       tkNumber,
+			tp:       TokenNumber,
 			hasDash:  true,
 			leftPos:  0,
 			rightPos: 4,
 		}},
 		"positive float": {input: "0.99", expected: token{
-			tp:       tkNumber,
+			tp:       TokenNumber,
 			isFloat:  true,
 			leftPos:  0,
 			rightPos: 4,
 		}},
 		"negative float": {input: "-0.99", expected: token{
-			tp:       tkNumber,
+			tp:       TokenNumber,
 			hasDash:  true,
 			isFloat:  true,
 			leftPos:  0,
 			rightPos: 5,
 		}},
 		"zero": {input: "0", expected: token{
-			tp:       tkNumber,
+			tp:       TokenNumber,
 			leftPos:  0,
 			rightPos: 1,
 		}},
 		"null": {input: `null`, expected: token{
-			tp:       tkNull,
+			tp:       TokenNull,
 			leftPos:  0,
 			rightPos: 4,
 		}},
 		"true": {input: `true`, expected: token{
-			tp:       tkBool,
+			tp:       TokenBool,
 			leftPos:  0,
 			rightPos: 4,
 		}},
 		"false": {input: `false`, expected: token{
-			tp:       tkBool,
+			tp:       TokenBool,
 			leftPos:  0,
 			rightPos: 5,
 		}},
 		"left {": {input: `{`, expected: token{
-			tp:       tkObjectStart,
+			tp:       TokenObjectStart,
 			leftPos:  0,
 			rightPos: 1,
 		}},
 		"right }": {input: "}", expected: token{
-			tp:       tkObjectEnd,
+			tp:       TokenObjectEnd,
 			leftPos:  0,
 			rightPos: 1,
 		}},
 		"left [": {input: `[`, expected: token{
-			tp:       tkArrayStart,
+			tp:       TokenArrayStart,
 			leftPos:  0,
 			rightPos: 1,
 		}},
 		"right ]": {input: "]", expected: token{
-			tp:       tkArrayEnd,
+			tp:       TokenArrayEnd,
 			leftPos:  0,
 			rightPos: 1,
 		}},
 		"colon :": {input: ":", expected: token{
-			tp:       tkColon,
+			tp:       TokenColon,
 			leftPos:  0,
 			rightPos: 1,
 		}},
 		"comma ,": {input: ",", expected: token{
-			tp:       tkComma,
+			tp:       TokenComma,
 			leftPos:  0,
 			rightPos: 1,
 		},
 		},
 		"whitespace space": {input: " ", expected: token{
-			tp:       tkWhiteSpace,
+			tp:       TokenWhiteSpace,
 			leftPos:  0,
 			rightPos: 1,
 		},
 		},
 		"whitespace linefeed": {input: "\r", expected: token{
-			tp:       tkWhiteSpace,
+			tp:       TokenWhiteSpace,
 			leftPos:  0,
 			rightPos: 1,
 		},
 		},
 		"whitespace carriage return": {input: "\n", expected: token{
-			tp:       tkWhiteSpace,
+			tp:       TokenWhiteSpace,
 			leftPos:  0,
 			rightPos: 1,
 		},
 		},
 		"whitespace tab": {input: "\t", expected: token{
-			tp:       tkWhiteSpace,
+			tp:       TokenWhiteSpace,
 			leftPos:  0,
 			rightPos: 1,
 		},
@@ -184,7 +184,8 @@ func Test_Scan_Simple_Tokens(t *testing.T) {
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
 			l := newLexer([]byte(tc.input))
-			tk := l.Scan()
+			tk, err := l.Scan()
+			assert.NoError(t, err)
 			assert.Equal(t, tc.expected, tk)
 		})
 	}
@@ -197,39 +198,39 @@ func Test_Scan_Multiple_Tokens(t *testing.T) {
 		// check curPos and lastPos inside code
 		expected []Type
 	}{
-		"object start and end":                  {input: "{}", expected: []Type{tkObjectStart, tkObjectEnd, tkEOF, tkEOF}},
-		"object end and start":                  {input: "}{", expected: []Type{tkObjectEnd, tkObjectStart, tkEOF}},
-		"object end and end":                    {input: "}}", expected: []Type{tkObjectEnd, tkObjectEnd, tkEOF}},
-		"object start, space and end":           {input: "{ }", expected: []Type{tkObjectStart, tkWhiteSpace, tkObjectEnd, tkEOF}},
-		"object start, linefeed and end":        {input: "{\r}", expected: []Type{tkObjectStart, tkWhiteSpace, tkObjectEnd, tkEOF}},
-		"object start, carriage return and end": {input: "{\n}", expected: []Type{tkObjectStart, tkWhiteSpace, tkObjectEnd, tkEOF}},
-		"object start, horizontal tab and end":  {input: "{\t}", expected: []Type{tkObjectStart, tkWhiteSpace, tkObjectEnd, tkEOF}},
-		`{"str": string}`:                       {input: `{"str": "string"}`, expected: []Type{tkObjectStart, tkString, tkColon, tkWhiteSpace, tkString, tkObjectEnd, tkEOF}},
-		`{"str": 123}`:                          {input: `{"str": 123}`, expected: []Type{tkObjectStart, tkString, tkColon, tkWhiteSpace, tkNumber, tkObjectEnd, tkEOF}},
-		`{"str": -123}`:                         {input: `{"str": -123}`, expected: []Type{tkObjectStart, tkString, tkColon, tkWhiteSpace, tkNumber, tkObjectEnd, tkEOF}},
-		`{"str": 0}`:                            {input: `{"str": 0}`, expected: []Type{tkObjectStart, tkString, tkColon, tkWhiteSpace, tkNumber, tkObjectEnd, tkEOF}},
-		`{"str": 0.99}`:                         {input: `{"str": 0.99}`, expected: []Type{tkObjectStart, tkString, tkColon, tkWhiteSpace, tkNumber, tkObjectEnd, tkEOF}},
-		`{"str": -0.99}`:                        {input: `{"str": -0.99}`, expected: []Type{tkObjectStart, tkString, tkColon, tkWhiteSpace, tkNumber, tkObjectEnd, tkEOF}},
-		`{"str": 123e456}`:                      {input: `{"str": 123e456}`, expected: []Type{tkObjectStart, tkString, tkColon, tkWhiteSpace, tkNumber, tkObjectEnd, tkEOF}},
-		`{"str": 123-e456}`:                     {input: `{"str": 123e-456}`, expected: []Type{tkObjectStart, tkString, tkColon, tkWhiteSpace, tkNumber, tkObjectEnd, tkEOF}},
-		`{"str": true}`:                         {input: `{"str": true}`, expected: []Type{tkObjectStart, tkString, tkColon, tkWhiteSpace, tkBool, tkObjectEnd, tkEOF}},
-		`{"str": false}`:                        {input: `{"str": false}`, expected: []Type{tkObjectStart, tkString, tkColon, tkWhiteSpace, tkBool, tkObjectEnd, tkEOF}},
-		`{"str": null}`:                         {input: `{"str": null}`, expected: []Type{tkObjectStart, tkString, tkColon, tkWhiteSpace, tkNull, tkObjectEnd, tkEOF}},
-		`{,}`:                                   {input: `{,}`, expected: []Type{tkObjectStart, tkComma, tkObjectEnd, tkEOF}},
-		`{,,}`:                                  {input: `{,,}`, expected: []Type{tkObjectStart, tkComma, tkComma, tkObjectEnd, tkEOF}},
-		`{123,}`:                                {input: `{123,}`, expected: []Type{tkObjectStart, tkNumber, tkComma, tkObjectEnd, tkEOF}},
-		`{1.234,}`:                              {input: `{1.234,}`, expected: []Type{tkObjectStart, tkNumber, tkComma, tkObjectEnd, tkEOF}},
-		`{"123",}`:                              {input: `{"123",}`, expected: []Type{tkObjectStart, tkString, tkComma, tkObjectEnd, tkEOF}},
-		`[]`:                                    {input: `[]`, expected: []Type{tkArrayStart, tkArrayEnd, tkEOF}},
-		`["1"]`:                                 {input: `["1"]`, expected: []Type{tkArrayStart, tkString, tkArrayEnd, tkEOF}},
-		`[1]`:                                   {input: `[1]`, expected: []Type{tkArrayStart, tkNumber, tkArrayEnd, tkEOF}},
-		`[1.23]`:                                {input: `[1.23]`, expected: []Type{tkArrayStart, tkNumber, tkArrayEnd, tkEOF}},
-		`[-1.23]`:                               {input: `[-1.23]`, expected: []Type{tkArrayStart, tkNumber, tkArrayEnd, tkEOF}},
-		`[1,2]`:                                 {input: `[1,2]`, expected: []Type{tkArrayStart, tkNumber, tkComma, tkNumber, tkArrayEnd, tkEOF}},
-		`"\ufffff"`:                             {input: `"\ufffff"`, expected: []Type{tkString, tkEOF}},
-		`"\uffffg"`:                             {input: `"\uffffg"`, expected: []Type{tkString, tkEOF}},
-		`"\uffff\uffff"`:                        {input: `"\uffff\uffff"`, expected: []Type{tkString, tkEOF}},
-		`"\"\/\\\b\f\n\r\t\uabcd"`:              {input: `"\"\/\\\b\f\n\r\t\uabcd"`, expected: []Type{tkString, tkEOF}},
+		"object start and end":                  {input: "{}", expected: []Type{TokenObjectStart, TokenObjectEnd, TokenEOF, TokenEOF}},
+		"object end and start":                  {input: "}{", expected: []Type{TokenObjectEnd, TokenObjectStart, TokenEOF}},
+		"object end and end":                    {input: "}}", expected: []Type{TokenObjectEnd, TokenObjectEnd, TokenEOF}},
+		"object start, space and end":           {input: "{ }", expected: []Type{TokenObjectStart, TokenWhiteSpace, TokenObjectEnd, TokenEOF}},
+		"object start, linefeed and end":        {input: "{\r}", expected: []Type{TokenObjectStart, TokenWhiteSpace, TokenObjectEnd, TokenEOF}},
+		"object start, carriage return and end": {input: "{\n}", expected: []Type{TokenObjectStart, TokenWhiteSpace, TokenObjectEnd, TokenEOF}},
+		"object start, horizontal tab and end":  {input: "{\t}", expected: []Type{TokenObjectStart, TokenWhiteSpace, TokenObjectEnd, TokenEOF}},
+		`{"str": string}`:                       {input: `{"str": "string"}`, expected: []Type{TokenObjectStart, TokenString, TokenColon, TokenWhiteSpace, TokenString, TokenObjectEnd, TokenEOF}},
+		`{"str": 123}`:                          {input: `{"str": 123}`, expected: []Type{TokenObjectStart, TokenString, TokenColon, TokenWhiteSpace, TokenNumber, TokenObjectEnd, TokenEOF}},
+		`{"str": -123}`:                         {input: `{"str": -123}`, expected: []Type{TokenObjectStart, TokenString, TokenColon, TokenWhiteSpace, TokenNumber, TokenObjectEnd, TokenEOF}},
+		`{"str": 0}`:                            {input: `{"str": 0}`, expected: []Type{TokenObjectStart, TokenString, TokenColon, TokenWhiteSpace, TokenNumber, TokenObjectEnd, TokenEOF}},
+		`{"str": 0.99}`:                         {input: `{"str": 0.99}`, expected: []Type{TokenObjectStart, TokenString, TokenColon, TokenWhiteSpace, TokenNumber, TokenObjectEnd, TokenEOF}},
+		`{"str": -0.99}`:                        {input: `{"str": -0.99}`, expected: []Type{TokenObjectStart, TokenString, TokenColon, TokenWhiteSpace, TokenNumber, TokenObjectEnd, TokenEOF}},
+		`{"str": 123e456}`:                      {input: `{"str": 123e456}`, expected: []Type{TokenObjectStart, TokenString, TokenColon, TokenWhiteSpace, TokenNumber, TokenObjectEnd, TokenEOF}},
+		`{"str": 123-e456}`:                     {input: `{"str": 123e-456}`, expected: []Type{TokenObjectStart, TokenString, TokenColon, TokenWhiteSpace, TokenNumber, TokenObjectEnd, TokenEOF}},
+		`{"str": true}`:                         {input: `{"str": true}`, expected: []Type{TokenObjectStart, TokenString, TokenColon, TokenWhiteSpace, TokenBool, TokenObjectEnd, TokenEOF}},
+		`{"str": false}`:                        {input: `{"str": false}`, expected: []Type{TokenObjectStart, TokenString, TokenColon, TokenWhiteSpace, TokenBool, TokenObjectEnd, TokenEOF}},
+		`{"str": null}`:                         {input: `{"str": null}`, expected: []Type{TokenObjectStart, TokenString, TokenColon, TokenWhiteSpace, TokenNull, TokenObjectEnd, TokenEOF}},
+		`{,}`:                                   {input: `{,}`, expected: []Type{TokenObjectStart, TokenComma, TokenObjectEnd, TokenEOF}},
+		`{,,}`:                                  {input: `{,,}`, expected: []Type{TokenObjectStart, TokenComma, TokenComma, TokenObjectEnd, TokenEOF}},
+		`{123,}`:                                {input: `{123,}`, expected: []Type{TokenObjectStart, TokenNumber, TokenComma, TokenObjectEnd, TokenEOF}},
+		`{1.234,}`:                              {input: `{1.234,}`, expected: []Type{TokenObjectStart, TokenNumber, TokenComma, TokenObjectEnd, TokenEOF}},
+		`{"123",}`:                              {input: `{"123",}`, expected: []Type{TokenObjectStart, TokenString, TokenComma, TokenObjectEnd, TokenEOF}},
+		`[]`:                                    {input: `[]`, expected: []Type{TokenArrayStart, TokenArrayEnd, TokenEOF}},
+		`["1"]`:                                 {input: `["1"]`, expected: []Type{TokenArrayStart, TokenString, TokenArrayEnd, TokenEOF}},
+		`[1]`:                                   {input: `[1]`, expected: []Type{TokenArrayStart, TokenNumber, TokenArrayEnd, TokenEOF}},
+		`[1.23]`:                                {input: `[1.23]`, expected: []Type{TokenArrayStart, TokenNumber, TokenArrayEnd, TokenEOF}},
+		`[-1.23]`:                               {input: `[-1.23]`, expected: []Type{TokenArrayStart, TokenNumber, TokenArrayEnd, TokenEOF}},
+		`[1,2]`:                                 {input: `[1,2]`, expected: []Type{TokenArrayStart, TokenNumber, TokenComma, TokenNumber, TokenArrayEnd, TokenEOF}},
+		`"\ufffff"`:                             {input: `"\ufffff"`, expected: []Type{TokenString, TokenEOF}},
+		`"\uffffg"`:                             {input: `"\uffffg"`, expected: []Type{TokenString, TokenEOF}},
+		`"\uffff\uffff"`:                        {input: `"\uffff\uffff"`, expected: []Type{TokenString, TokenEOF}},
+		`"\"\/\\\b\f\n\r\t\uabcd"`:              {input: `"\"\/\\\b\f\n\r\t\uabcd"`, expected: []Type{TokenString, TokenEOF}},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
@@ -237,9 +238,11 @@ func Test_Scan_Multiple_Tokens(t *testing.T) {
 			var counter, lastPos int
 			var tk token
 
-			for tk.tp != tkEOF {
+			for tk.tp != TokenEOF {
 				assert.LessOrEqual(t, counter, len(tc.expected))
-				tk = l.Scan()
+				var err error
+				tk, err = l.Scan()
+				assert.NoError(t, err)
 
 				assert.Equal(t, tc.expected[counter].String(), tk.tp.String(), "token types don't match")
 				assert.Equal(t, lastPos, tk.leftPos, "positions are wrong")
@@ -253,25 +256,99 @@ func Test_Scan_Multiple_Tokens(t *testing.T) {
 	}
 }
 
-func Test_Scan_Panic(t *testing.T) {
+func Test_Scan_Error(t *testing.T) {
 	testCases := map[string]struct {
-		input string
+		input    string
+		err      error
+		position Position
 	}{
-		`invalid string \d`:     {input: `"\d"`},
-		`invalid string \uabcg`: {input: `"\uabcg"`},
-		`invalid string "abc`:   {input: `"abc`},
-		`invalid bool truu`:     {input: "truu"},
-		`invalid bool falss `:   {input: "falss"},
-		`invalid null nul `:     {input: "nul"},
-		`invalid null nul1 `:    {input: "nul1"},
+		`invalid string \d`:     {input: `"\d"`, err: ErrInvalidString, position: Position{Offset: 2, Line: 1, Column: 3}},
+		`invalid string \uabcg`: {input: `"\uabcg"`, err: ErrInvalidString, position: Position{Offset: 3, Line: 1, Column: 4}},
+		`invalid string "abc`:   {input: `"abc`, err: ErrUnexpectedEOF, position: Position{Offset: 4, Line: 1, Column: 5}},
+		`invalid string "\u12`:  {input: `"\u12`, err: ErrUnexpectedEOF, position: Position{Offset: 5, Line: 1, Column: 6}},
+		`invalid string "\`:     {input: `"\`, err: ErrUnexpectedEOF, position: Position{Offset: 2, Line: 1, Column: 3}},
+		`invalid bool truu`:     {input: "truu", err: ErrInvalidLiteral, position: Position{Offset: 0, Line: 1, Column: 1}},
+		`invalid bool falss `:   {input: "falss", err: ErrInvalidLiteral, position: Position{Offset: 0, Line: 1, Column: 1}},
+		`truncated bool tru`:    {input: "tru", err: ErrUnexpectedEOF, position: Position{Offset: 3, Line: 1, Column: 4}},
+		`truncated bool f`:      {input: "f", err: ErrUnexpectedEOF, position: Position{Offset: 1, Line: 1, Column: 2}},
+		`truncated null nul `:   {input: "nul", err: ErrUnexpectedEOF, position: Position{Offset: 3, Line: 1, Column: 4}},
+		`invalid bool tx`:       {input: "tx", err: ErrInvalidLiteral, position: Position{Offset: 0, Line: 1, Column: 1}},
+		`invalid null nul1 `:    {input: "nul1", err: ErrInvalidLiteral, position: Position{Offset: 0, Line: 1, Column: 1}},
+		`invalid character x`:   {input: "x", err: ErrInvalidCharacter, position: Position{Offset: 0, Line: 1, Column: 1}},
+		`invalid number 01`:     {input: "01", err: ErrInvalidNumber, position: Position{Offset: 1, Line: 1, Column: 2}},
+		`invalid number -01`:    {input: "-01", err: ErrInvalidNumber, position: Position{Offset: 2, Line: 1, Column: 3}},
+		`invalid number 1.2.3`:  {input: "1.2.3", err: ErrInvalidNumber, position: Position{Offset: 3, Line: 1, Column: 4}},
+		`invalid number 1-2`:    {input: "1-2", err: ErrInvalidNumber, position: Position{Offset: 1, Line: 1, Column: 2}},
+		`invalid number --5`:    {input: "--5", err: ErrInvalidNumber, position: Position{Offset: 1, Line: 1, Column: 2}},
+		`invalid number -a`:     {input: "-a", err: ErrInvalidNumber, position: Position{Offset: 1, Line: 1, Column: 2}},
+		`invalid number 1.e1`:   {input: "1.e1", err: ErrInvalidNumber, position: Position{Offset: 2, Line: 1, Column: 3}},
+		`invalid number 1e+`:    {input: "1e+ ", err: ErrInvalidNumber, position: Position{Offset: 3, Line: 1, Column: 4}},
+		`invalid number 1e1e1`:  {input: "1e1e1", err: ErrInvalidNumber, position: Position{Offset: 3, Line: 1, Column: 4}},
+		`invalid number 1e`:     {input: "1e", err: ErrUnexpectedEOF, position: Position{Offset: 2, Line: 1, Column: 3}},
+		`invalid number 1.`:     {input: "1.", err: ErrUnexpectedEOF, position: Position{Offset: 2, Line: 1, Column: 3}},
+		`invalid number -`:      {input: "-", err: ErrUnexpectedEOF, position: Position{Offset: 1, Line: 1, Column: 2}},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			assert.Panics(t, func() {
-				l := newLexer([]byte(tc.input))
-				_ = l.Scan()
-			})
+			l := newLexer([]byte(tc.input))
+			_, err := l.Scan()
+			assert.ErrorIs(t, err, tc.err)
+
+			var pe *ParseError
+			assert.ErrorAs(t, err, &pe)
+			assert.Equal(t, tc.position, pe.Position)
 		})
 	}
+}
 
+func Test_Scan_Number(t *testing.T) {
+	testCases := map[string]struct {
+		hasDash, isFloat bool
+	}{
+		"0":         {},
+		"-0":        {hasDash: true},
+		"10":        {},
+		"0.5":       {isFloat: true},
+		"-10.05":    {hasDash: true, isFloat: true},
+		"1e5":       {isFloat: true},
+		"1E+5":      {isFloat: true},
+		"-0.5e-05":  {hasDash: true, isFloat: true},
+		"123456789": {},
+	}
+	for input, tc := range testCases {
+		t.Run(input, func(t *testing.T) {
+			// the number ends before a delimiter
+			l := newLexer([]byte(input + ","))
+			tk, err := l.Scan()
+			assert.NoError(t, err)
+			assert.Equal(t, token{tp: TokenNumber, hasDash: tc.hasDash, isFloat: tc.isFloat, rightPos: len(input)}, tk)
+		})
+	}
+}
+
+func Test_Scan_Position(t *testing.T) {
+	// the position of a token is available until the next token is scanned
+	l := newLexer([]byte("[\n  1,\r\n 2, \"a\nb\"]"))
+	var positions []Position
+	for {
+		tk, err := l.Scan()
+		assert.NoError(t, err)
+		if tk.tp == TokenEOF {
+			break
+		}
+		if tk.tp != TokenWhiteSpace {
+			positions = append(positions, l.position(tk.leftPos))
+		}
+	}
+	assert.Equal(t, []Position{
+		{Offset: 0, Line: 1, Column: 1},
+		{Offset: 4, Line: 2, Column: 3},
+		{Offset: 5, Line: 2, Column: 4},
+		{Offset: 9, Line: 3, Column: 2},
+		{Offset: 10, Line: 3, Column: 3},
+		// the string contains a newline
+		{Offset: 12, Line: 3, Column: 5},
+		{Offset: 17, Line: 4, Column: 3},
+	}, positions)
+	assert.Equal(t, Position{Offset: 18, Line: 4, Column: 4}, l.position(18))
 }
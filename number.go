@@ -0,0 +1,115 @@
+package astjson
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxExponent limits the exponent of numbers converted to big.Rat and big.Float,
+// which prevents a tiny literal like 1e999999999 from exhausting memory.
+const maxExponent = 10000
+
+// NumberLiteral is the literal text of a json number like json.Number in encoding/json.
+// The Decoder sets it by NumberAst.String, which is the original text if the Parser is
+// created with WithNumberLiteral.
+type NumberLiteral string
+
+// String returns the literal text of the number.
+func (n NumberLiteral) String() string {
+	return string(n)
+}
+
+// Float64 returns the number as a float64.
+func (n NumberLiteral) Float64() (float64, error) {
+	return strconv.ParseFloat(string(n), 64)
+}
+
+// Int64 returns the number as an int64.
+func (n NumberLiteral) Int64() (int64, error) {
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// String returns the literal text of the number kept by WithNumberLiteral, or the shortest
+// text represents the stored value otherwise.
+func (n NumberAst) String() string {
+	if n.literal != "" {
+		return n.literal
+	}
+	switch n.Nt {
+	case unsignedInteger:
+		return strconv.FormatUint(n.u, 10)
+	case integer:
+		return strconv.FormatInt(n.i, 10)
+	}
+	return strconv.FormatFloat(n.f, 'g', -1, 64)
+}
+
+// Rat returns the exact value of the number represented by String. It returns nil if the
+// number is out of range, such as an infinity or an exponent greater than 10000.
+func (n NumberAst) Rat() *big.Rat {
+	text := n.String()
+	if !exponentInRange(text) {
+		return nil
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil
+	}
+	return r
+}
+
+// BigInt returns the integer value of the number, it reports false if the number has
+// a fraction or is out of range. Note that 1.0 and 1e2 are integers.
+func (n NumberAst) BigInt() (*big.Int, bool) {
+	r := n.Rat()
+	if r == nil || !r.IsInt() {
+		return nil, false
+	}
+	return new(big.Int).Set(r.Num()), true
+}
+
+// BigFloat returns the number as a big.Float whose precision is enough to hold all the
+// digits of String, it returns nil if the number is out of range.
+func (n NumberAst) BigFloat() *big.Float {
+	text := n.String()
+	if !exponentInRange(text) {
+		return nil
+	}
+	// a decimal digit needs less than 4 bits
+	prec := uint(len(text))*4 + 64
+	f, _, err := big.ParseFloat(text, 10, prec, big.ToNearestEven)
+	if err != nil {
+		return nil
+	}
+	return f
+}
+
+// Exact reports whether the stored int64, uint64 or float64 of the number is exactly the
+// literal text, for example, 0.1 and 18446744073709551617 aren't exact. It's always true if
+// the literal text isn't kept by WithNumberLiteral.
+func (n NumberAst) Exact() bool {
+	if n.literal == "" {
+		return true
+	}
+	if n.Nt != floatNumber {
+		// the integer is stored only if it could be parsed without loss
+		return true
+	}
+	if math.IsInf(n.f, 0) {
+		return false
+	}
+	r := n.Rat()
+	return r != nil && new(big.Rat).SetFloat64(n.f).Cmp(r) == 0
+}
+
+// exponentInRange reports whether the exponent part of the number text is acceptable.
+func exponentInRange(text string) bool {
+	i := strings.IndexAny(text, "eE")
+	if i < 0 {
+		return !strings.ContainsAny(text, "IN")
+	}
+	exp, err := strconv.Atoi(text[i+1:])
+	return err == nil && -maxExponent <= exp && exp <= maxExponent
+}
@@ -1,43 +1,258 @@
 package astjson
 
 import (
+	"fmt"
+	"io"
 	"strconv"
+	"strings"
 )
 
-// Parse transforms the json bytes to AST value, it will return nil or panic
-// when the input is invalid.
-// todo: fix me: return error instead of panic
-func Parse(bs []byte) *Value {
-	return NewParser(bs).Parse()
+// Parse transforms the json bytes to AST value, it returns nil when the input is empty
+// and panics when the input is invalid. See ParseBytes for the error-returning version.
+func Parse(bs []byte, opts ...ParserOption) *Value {
+	return NewParser(bs, opts...).Parse()
+}
+
+// ParseBytes transforms the json bytes to AST value, it returns nil when the input is empty
+// and a *ParseError when the input is invalid.
+func ParseBytes(bs []byte, opts ...ParserOption) (*Value, error) {
+	return NewParser(bs, opts...).ParseWithError()
 }
 
 // Parser helps to parse the bytes to AST value
 type Parser struct {
 	bs []byte
 	l  *lexer
+
+	// spans reports whether to record the Span of values and keys
+	spans bool
+	// homogeneousArrays reports whether to reject arrays whose elements have different node types
+	homogeneousArrays bool
+	// singleDocument reports whether to reject the data after the top-level value
+	singleDocument bool
+	// numberLiteral reports whether to keep the literal text of numbers
+	numberLiteral bool
+
+	// the limits of untrusted input, zero means no limit.
+	// the max document size is checked by lexer.
+	maxDepth        int
+	maxStringLength int
+	maxObjectKeys   int
+	maxArrayLength  int
+	// depth is the count of arrays and objects being parsed
+	depth int
+}
+
+// ParserOption customizes the behaviors of a Parser.
+type ParserOption func(p *Parser)
+
+// WithSpans records the Span of every Value and every object key during parsing,
+// which helps to point out the location of a Value in the original document.
+func WithSpans() ParserOption {
+	return func(p *Parser) {
+		p.spans = true
+	}
+}
+
+// WithNumberLiteral keeps the literal text of every number, so the precise value is available
+// by NumberAst.String, BigInt, BigFloat and Rat, and the Encoder writes the original text.
+// The numbers out of the range of float64 are accepted as well, their float64 values are infinities.
+func WithNumberLiteral() ParserOption {
+	return func(p *Parser) {
+		p.numberLiteral = true
+	}
+}
+
+// WithMaxDepth limits how deep arrays and objects are nested, for example, the depth of [{}]
+// is 2. It prevents the deeply nested input from exhausting the stack.
+func WithMaxDepth(depth int) ParserOption {
+	return func(p *Parser) {
+		p.maxDepth = depth
+	}
 }
 
-// Parse returns the valid AST value, nil or panic
-// todo: deprecated it because we want to return error instead of panic
+// WithMaxDocumentSize limits the length of input in bytes, all bytes are counted including
+// the whitespace and the data after the top-level value. The Parser created by NewParser
+// rejects the longer input before parsing, and the one created by NewStreamParser counts
+// every byte read from the io.Reader and stops reading when the limit is exceeded.
+func WithMaxDocumentSize(size int) ParserOption {
+	return func(p *Parser) {
+		p.l.maxSize = size
+	}
+}
+
+// WithMaxStringLength limits the length in bytes of every unescaped string and object key.
+func WithMaxStringLength(length int) ParserOption {
+	return func(p *Parser) {
+		p.maxStringLength = length
+	}
+}
+
+// WithMaxObjectKeys limits the count of keys inside every object.
+func WithMaxObjectKeys(count int) ParserOption {
+	return func(p *Parser) {
+		p.maxObjectKeys = count
+	}
+}
+
+// WithMaxArrayLength limits the count of elements inside every array.
+func WithMaxArrayLength(length int) ParserOption {
+	return func(p *Parser) {
+		p.maxArrayLength = length
+	}
+}
+
+// Parse returns the valid AST value, nil or panic.
+// It's kept for compatibility, prefer ParseWithError to handle the untrusted input.
 func (p *Parser) Parse() *Value {
+	val, err := p.ParseWithError()
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+// ParseWithError returns the valid AST value, or nil when the input is empty.
+// It never panics, a *ParseError is returned when the input is invalid.
+// For the Parser created by NewStreamParser, it continues from where the last parsing stops
+// and the error of the io.Reader is returned as it is.
+func (p *Parser) ParseWithError() (*Value, error) {
 	p.l.Reset()
-	tk := p.nextExceptWhitespace()
+	val, err := p.Next()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.singleDocument {
+		tk, err := p.nextExceptWhitespace()
+		if err != nil {
+			return nil, err
+		}
+		if tk.tp != TokenEOF {
+			return nil, p.l.errorf(tk.leftPos, tk.tp, "end of input", ErrTrailingData)
+		}
+	}
+	return val, nil
+}
+
+// WithSingleDocument makes ParseWithError reject any non-whitespace data after the top-level
+// value, which is ignored by default. It doesn't affect Next and ParseAll.
+func WithSingleDocument() ParserOption {
+	return func(p *Parser) {
+		p.singleDocument = true
+	}
+}
+
+// Next parses the next top-level value from where the last parsing stops, which helps to
+// parse the newline-delimited json and the concatenated json such as {"a": 1}{"b": 2}.
+// It returns io.EOF when there is no more value, and the Parser shouldn't be used anymore
+// after it returns an error.
+func (p *Parser) Next() (*Value, error) {
+	tk, err := p.nextExceptWhitespace()
+	if err != nil {
+		return nil, err
+	}
+	if tk.tp == TokenEOF {
+		return nil, io.EOF
+	}
 	return p.parse(tk)
 }
 
+// ParseAll parses all top-level values from the beginning of input.
+// See Next to iterate the values one by one.
+func (p *Parser) ParseAll() ([]*Value, error) {
+	p.l.Reset()
+	var values []*Value
+	for {
+		val, err := p.Next()
+		if err == io.EOF {
+			return values, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, val)
+	}
+}
+
 // parse helps to get a whole object, array or a literal type.
-func (p *Parser) parse(tk token) *Value {
+func (p *Parser) parse(tk token) (*Value, error) {
+	var (
+		val *Value
+		err error
+	)
+	// the position is computed before scanning the rest of value as lexer only knows
+	// the lines around the last token
+	start := p.l.position(tk.leftPos)
 	switch tk.tp {
-	case tkNumber, tkString, tkBool, tkNull:
-		return literal(p.bs, tk)
-	case tkEOF:
-		return nil
-	case tkArrayStart:
-		return p.arrayParser()
-	case tkObjectStart:
-		return p.objectParser()
+	case TokenNumber, TokenString, TokenBool, TokenNull:
+		if val, err = literal(p.l.text(tk), tk, p.numberLiteral); err != nil {
+			return nil, p.l.errorf(tk.leftPos, tk.tp, "", err)
+		}
+		if val.NodeType == String {
+			err = p.verifyString(tk, string(val.AstValue.(StringAst)))
+		}
+	case TokenArrayStart, TokenObjectStart:
+		if p.maxDepth > 0 && p.depth >= p.maxDepth {
+			return nil, p.l.errorf(tk.leftPos, tk.tp, "", ErrMaxDepth)
+		}
+		p.depth++
+		if tk.tp == TokenArrayStart {
+			val, err = p.arrayParser()
+		} else {
+			val, err = p.objectParser()
+		}
+		p.depth--
 	default:
-		panic("invalid json syntax")
+		return nil, p.unexpected(tk, "a json value")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if p.spans {
+		// the lexer stops right after the last token of the value
+		val.Span = &Span{Start: start, End: p.l.position(p.l.curPos)}
+	}
+	return val, nil
+}
+
+// Raw returns the original bytes of span in the document, for example, the escaped
+// content of a string. The span should be recorded by the Parser created with WithSpans.
+// It returns nil for the Parser created by NewStreamParser as the input isn't kept.
+func (p *Parser) Raw(span Span) []byte {
+	if p.l.r != nil {
+		return nil
+	}
+	return p.bs[span.Start.Offset:span.End.Offset]
+}
+
+// verifyString verifies the unescaped string str of token tk doesn't exceed the max length.
+func (p *Parser) verifyString(tk token, str string) error {
+	if p.maxStringLength > 0 && len(str) > p.maxStringLength {
+		return p.l.errorf(tk.leftPos, tk.tp, "", ErrMaxStringLength)
+	}
+	return nil
+}
+
+// unexpected reports tk is not the expected one.
+func (p *Parser) unexpected(tk token, expected string) *ParseError {
+	err := ErrUnexpectedToken
+	if tk.tp == TokenEOF {
+		err = ErrUnexpectedEOF
+	}
+	return p.l.errorf(tk.leftPos, tk.tp, expected, err)
+}
+
+// WithHomogeneousArrays rejects the arrays whose elements have different node types,
+// for example, [1, "a"]. Such arrays are valid json and are accepted by default.
+// See ShouldBeHomogeneousArray to verify it by Walker.
+func WithHomogeneousArrays() ParserOption {
+	return func(p *Parser) {
+		p.homogeneousArrays = true
 	}
 }
 
@@ -56,102 +271,160 @@ func (a *ArrayAst) verifyNextType(ntp NodeType) bool {
 	return false
 }
 
-// arrayParser parses the remained part of an array after tkArrayStart is found before.
-func (p *Parser) arrayParser() *Value {
+// arrayParser parses the remained part of an array after TokenArrayStart is found before.
+func (p *Parser) arrayParser() (*Value, error) {
 	var ar ArrayAst
 
 	for {
-		tk := p.nextExceptWhitespace()
-		if tk.tp == tkArrayEnd {
+		tk, err := p.nextExceptWhitespace()
+		if err != nil {
+			return nil, err
+		}
+		if tk.tp == TokenArrayEnd && len(ar.Values) == 0 {
 			return &Value{
 				NodeType: Array,
 				AstValue: &ArrayAst{},
-			}
+			}, nil
+		}
+		if p.maxArrayLength > 0 && len(ar.Values) >= p.maxArrayLength {
+			return nil, p.l.errorf(tk.leftPos, tk.tp, "", ErrMaxArrayLength)
+		}
+		pos := p.l.position(tk.leftPos)
+		val, err := p.parse(tk)
+		if err != nil {
+			return nil, err
 		}
-		val := p.parse(tk)
 
-		if ar.verifyNextType(val.NodeType) {
-			ar.Values = append(ar.Values, *val)
-		} else {
-			panic("inconsistent array value type")
+		if p.homogeneousArrays && !ar.verifyNextType(val.NodeType) {
+			return nil, &ParseError{
+				Position: pos,
+				Token:    tk.tp,
+				Expected: strings.ToLower(ar.Values[0].NodeType.String()) + " like the first element",
+				Err:      ErrInconsistentArray,
+			}
 		}
+		ar.Values = append(ar.Values, *val)
 
 		// check whether an array ends
-		then := p.nextExceptWhitespace()
-		if then.tp == tkArrayEnd {
+		then, err := p.nextExceptWhitespace()
+		if err != nil {
+			return nil, err
+		}
+		if then.tp == TokenArrayEnd {
 			break
-		} else if then.tp == tkComma {
-			continue
-		} else {
-			panic("invalid token after colon")
+		} else if then.tp != TokenComma {
+			return nil, p.unexpected(then, "',' or ']'")
 		}
 	}
 
 	return &Value{
 		NodeType: Array,
 		AstValue: &ar,
-	}
+	}, nil
 }
 
-// objectParser parses the remained part of an array after tkObjectStart is found before.
-func (p *Parser) objectParser() *Value {
-	var v ObjectAst
-	v.KvMap = map[string]Value{}
+// objectParser parses the remained part of an array after TokenObjectStart is found before.
+func (p *Parser) objectParser() (*Value, error) {
+	v := NewObjectAst()
 
 	for {
-		start := p.nextExceptWhitespace()
+		start, err := p.nextExceptWhitespace()
+		if err != nil {
+			return nil, err
+		}
 		// an object is empty {}
-		if start.tp == tkObjectEnd {
+		if start.tp == TokenObjectEnd && v.Len() == 0 {
 			return &Value{
 				NodeType: Object,
-				AstValue: &v,
-			}
+				AstValue: v,
+			}, nil
 		}
 
-		if start.tp != tkString {
-			panic("Invalid json schema for key")
+		if start.tp != TokenString {
+			return nil, p.unexpected(start, "a string key")
 		}
-		value := literal(p.bs, start)
+		// the string literal never fails as the escape sequences have been verified by lexer
+		value, _ := literal(p.l.text(start), start, false)
 		key := string(value.AstValue.(StringAst))
+		if err = p.verifyString(start, key); err != nil {
+			return nil, err
+		}
+		if p.maxObjectKeys > 0 && v.Len() >= p.maxObjectKeys {
+			return nil, p.l.errorf(start.leftPos, start.tp, "", ErrMaxObjectKeys)
+		}
+		keySpan := Span{Start: p.l.position(start.leftPos), End: p.l.position(start.rightPos)}
 
-		if tkColon != p.nextExceptWhitespace().tp {
-			panic("invalid json schema after key")
+		colon, err := p.nextExceptWhitespace()
+		if err != nil {
+			return nil, err
+		}
+		if colon.tp != TokenColon {
+			return nil, p.unexpected(colon, "':'")
 		}
-		if _, ok := v.KvMap[key]; ok {
-			panic("duplicated key")
+		if _, ok := v.Get(key); ok {
+			return nil, &ParseError{Position: keySpan.Start, Token: start.tp, Err: ErrDuplicatedKey}
 		}
 
-		val := p.parse(p.nextExceptWhitespace())
-		v.KvMap[key] = *val
+		tk, err := p.nextExceptWhitespace()
+		if err != nil {
+			return nil, err
+		}
+		val, err := p.parse(tk)
+		if err != nil {
+			return nil, err
+		}
+		v.Set(key, *val)
+		if p.spans {
+			if v.keySpans == nil {
+				v.keySpans = map[string]Span{}
+			}
+			v.keySpans[key] = keySpan
+		}
 
 		// check whether an object ends
-		// todo: refine me: the logic here is duplicated with the beginning of the for loop
-		then := p.nextExceptWhitespace()
-		if then.tp == tkObjectEnd {
+		then, err := p.nextExceptWhitespace()
+		if err != nil {
+			return nil, err
+		}
+		if then.tp == TokenObjectEnd {
 			break
-		} else if then.tp == tkComma {
-			continue
-		} else {
-			panic("invalid token after colon")
+		} else if then.tp != TokenComma {
+			return nil, p.unexpected(then, "',' or '}'")
 		}
 	}
 
 	return &Value{
 		NodeType: Object,
-		AstValue: &v,
-	}
+		AstValue: v,
+	}, nil
 }
 
 // NewParser creates a new Parser to parse full json bytes to AST node.
-func NewParser(bs []byte) *Parser {
-	return &Parser{
+func NewParser(bs []byte, opts ...ParserOption) *Parser {
+	p := &Parser{
 		bs: bs,
 		l:  newLexer(bs),
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// NewStreamParser creates a new Parser which reads json from r on demand, so the input
+// needn't be loaded into memory at once. The produced AST is same as NewParser.
+func NewStreamParser(r io.Reader, opts ...ParserOption) *Parser {
+	p := &Parser{
+		l: newReaderLexer(r),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // next keep retrieving tokens and return the token which type is not contained inside skips.
-func (p *Parser) next(skips ...Type) token {
+func (p *Parser) next(skips ...Type) (token, error) {
 	shouldSkip := func(tk Type) bool {
 		for _, skip := range skips {
 			if tk == skip {
@@ -161,68 +434,81 @@ func (p *Parser) next(skips ...Type) token {
 		return false
 	}
 
-	tk := p.l.Scan()
-	for shouldSkip(tk.tp) {
-		tk = p.l.Scan()
+	for {
+		tk, err := p.l.Scan()
+		if err != nil || !shouldSkip(tk.tp) {
+			return tk, err
+		}
 	}
-	return tk
 }
 
-// nextExceptWhitespace returns the token which is not a tkWhiteSpace type.
-func (p *Parser) nextExceptWhitespace() token {
-	return p.next(tkWhiteSpace)
+// nextExceptWhitespace returns the token which is not a TokenWhiteSpace type.
+func (p *Parser) nextExceptWhitespace() (token, error) {
+	return p.next(TokenWhiteSpace)
 }
 
-// literal constructs the AST value for Number, String, Bool and Null type.
-// The AstValue inside Value is not a pointer.
-func literal(bs []byte, tk token) *Value {
+// literal constructs the AST value for Number, String, Bool and Null type from
+// the bytes text of token tk. The AstValue inside Value is not a pointer.
+// The literal text of number is kept if keepNumber is true.
+func literal(text []byte, tk token, keepNumber bool) (*Value, error) {
 	var v Value
 	switch tk.tp {
-	case tkString:
+	case TokenString:
 		v.NodeType = String
-		// remove left and right "
-		// todo: check whether use pointer
-		v.AstValue = StringAst(bs[tk.leftPos+1 : tk.rightPos-1])
-	case tkBool:
+		// remove left and right ", the escape sequences have been verified by lexer
+		str, _ := unescape(text[1 : len(text)-1])
+		v.AstValue = StringAst(str)
+	case TokenBool:
 		v.NodeType = Bool
-		b, _ := strconv.ParseBool(string(bs[tk.leftPos:tk.rightPos]))
-		// todo: ditto
-		v.AstValue = BoolAst(b)
-	case tkNumber:
+		v.AstValue = BoolAst(text[0] == 't')
+	case TokenNumber:
 		v.NodeType = Number
-		// todo: ditto
-		v.AstValue = tokenNumber(bs, tk)
-	case tkNull:
+		number, err := tokenNumber(text, tk, keepNumber)
+		if err != nil {
+			return nil, err
+		}
+		v.AstValue = number
+	case TokenNull:
 		// the AstValue of those types are useless
 		v.NodeType = Null
 		v.AstValue = &NullAst{}
 	}
-	return &v
+	return &v, nil
 }
 
-// tokenNumber converts a tkNumber token to a precise number(float, int or uint).
-// it panics if the token type isn't tkNumber
-func tokenNumber(bs []byte, tk token) NumberAst {
-	if tk.tp != tkNumber {
-		panic("token must be a tkNumber token")
+// tokenNumber converts a TokenNumber token to a precise number(float, int or uint).
+// The integer overflows int64 or uint64 is stored as a float, and the error is returned
+// when the number is out of the range of float64 unless the literal text is kept.
+// it panics if the token type isn't TokenNumber
+func tokenNumber(text []byte, tk token, keepLiteral bool) (NumberAst, error) {
+	if tk.tp != TokenNumber {
+		panic("token must be a TokenNumber token")
 	}
 	var numberAst NumberAst
-
-	if tk.isFloat {
-		f, _ := strconv.ParseFloat(string(bs[tk.leftPos:tk.rightPos]), 64)
-		numberAst.Nt = floatNumber
-		numberAst.f = f
-		return numberAst
+	if keepLiteral {
+		numberAst.literal = string(text)
 	}
-	if tk.hasDash {
-		i, _ := strconv.ParseInt(string(bs[tk.leftPos:tk.rightPos]), 10, 64)
-		numberAst.Nt = integer
-		numberAst.i = i
-		return numberAst
+
+	if !tk.isFloat {
+		// the grammar has been verified by lexer, so only the range error could happen
+		if tk.hasDash {
+			if i, err := strconv.ParseInt(string(text), 10, 64); err == nil {
+				numberAst.Nt = integer
+				numberAst.i = i
+				return numberAst, nil
+			}
+		} else if u, err := strconv.ParseUint(string(text), 10, 64); err == nil {
+			numberAst.Nt = unsignedInteger
+			numberAst.u = u
+			return numberAst, nil
+		}
 	}
 
-	u, _ := strconv.ParseUint(string(bs[tk.leftPos:tk.rightPos]), 10, 64)
-	numberAst.Nt = unsignedInteger
-	numberAst.u = u
-	return numberAst
+	f, err := strconv.ParseFloat(string(text), 64)
+	if err != nil && !keepLiteral {
+		return numberAst, fmt.Errorf("%w: %s is out of range", ErrInvalidNumber, text)
+	}
+	numberAst.Nt = floatNumber
+	numberAst.f = f
+	return numberAst, nil
 }
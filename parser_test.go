@@ -1,7 +1,11 @@
 package astjson
 
 import (
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -80,7 +84,7 @@ func Test_Parse_Object(t *testing.T) {
 			input: "{}",
 			expected: &Value{
 				NodeType: Object,
-				AstValue: &ObjectAst{map[string]Value{}},
+				AstValue: NewObjectAst(),
 			},
 		},
 		{
@@ -88,9 +92,8 @@ func Test_Parse_Object(t *testing.T) {
 			input: `{"123": "123"}`,
 			expected: &Value{
 				NodeType: Object,
-				AstValue: &ObjectAst{map[string]Value{
-					"123": {NodeType: String, AstValue: StringAst("123")}},
-				},
+				AstValue: NewObjectAst([]Member{
+					{"123", Value{NodeType: String, AstValue: StringAst("123")}}}...),
 			},
 		},
 		{
@@ -98,12 +101,11 @@ func Test_Parse_Object(t *testing.T) {
 			input: `{"123": 123}`,
 			expected: &Value{
 				NodeType: Object,
-				AstValue: &ObjectAst{map[string]Value{
-					"123": {NodeType: Number, AstValue: NumberAst{
+				AstValue: NewObjectAst([]Member{
+					{"123", Value{NodeType: Number, AstValue: NumberAst{
 						Nt: unsignedInteger,
 						u:  123,
-					}}},
-				},
+					}}}}...),
 			},
 		},
 		{
@@ -111,9 +113,8 @@ func Test_Parse_Object(t *testing.T) {
 			input: `{"123": true}`,
 			expected: &Value{
 				NodeType: Object,
-				AstValue: &ObjectAst{map[string]Value{
-					"123": {NodeType: Bool, AstValue: BoolAst(true)}},
-				},
+				AstValue: NewObjectAst([]Member{
+					{"123", Value{NodeType: Bool, AstValue: BoolAst(true)}}}...),
 			},
 		},
 		{
@@ -121,9 +122,8 @@ func Test_Parse_Object(t *testing.T) {
 			input: `{"123": false}`,
 			expected: &Value{
 				NodeType: Object,
-				AstValue: &ObjectAst{map[string]Value{
-					"123": {NodeType: Bool, AstValue: BoolAst(false)}},
-				},
+				AstValue: NewObjectAst([]Member{
+					{"123", Value{NodeType: Bool, AstValue: BoolAst(false)}}}...),
 			},
 		},
 		{
@@ -131,9 +131,8 @@ func Test_Parse_Object(t *testing.T) {
 			input: `{"123": null}`,
 			expected: &Value{
 				NodeType: Object,
-				AstValue: &ObjectAst{map[string]Value{
-					"123": {NodeType: Null, AstValue: &NullAst{}}},
-				},
+				AstValue: NewObjectAst([]Member{
+					{"123", Value{NodeType: Null, AstValue: &NullAst{}}}}...),
 			},
 		},
 		{
@@ -141,11 +140,10 @@ func Test_Parse_Object(t *testing.T) {
 			input: `{"123": null, "12": null}`,
 			expected: &Value{
 				NodeType: Object,
-				AstValue: &ObjectAst{map[string]Value{
-					"123": {NodeType: Null, AstValue: &NullAst{}},
-					"12":  {NodeType: Null, AstValue: &NullAst{}},
-				},
-				},
+				AstValue: NewObjectAst([]Member{
+					{"123", Value{NodeType: Null, AstValue: &NullAst{}}},
+					{"12", Value{NodeType: Null, AstValue: &NullAst{}}},
+				}...),
 			},
 		},
 	}
@@ -238,6 +236,20 @@ func Test_Parse_Array(t *testing.T) {
 				}},
 			},
 		},
+		{
+			name:  "mixed array",
+			input: `[1, "a", null, [true], {}]`,
+			expected: &Value{
+				NodeType: Array,
+				AstValue: &ArrayAst{[]Value{
+					{NodeType: Number, AstValue: NumberAst{Nt: unsignedInteger, u: 1}},
+					{NodeType: String, AstValue: StringAst("a")},
+					{NodeType: Null, AstValue: &NullAst{}},
+					{NodeType: Array, AstValue: &ArrayAst{[]Value{{NodeType: Bool, AstValue: BoolAst(true)}}}},
+					{NodeType: Object, AstValue: NewObjectAst()},
+				}},
+			},
+		},
 		{
 			name:  "empty array of array",
 			input: `[ [] ]`,
@@ -309,56 +321,56 @@ func Test_Parse_Mixture(t *testing.T) {
 			}`,
 			expected: &Value{
 				NodeType: Object,
-				AstValue: &ObjectAst{map[string]Value{
-					"str":   {NodeType: String, AstValue: StringAst(`123\b\t\r\n`)},
-					"num":   {NodeType: Number, AstValue: NumberAst{Nt: unsignedInteger, u: 123}},
-					"bool":  {NodeType: Bool, AstValue: BoolAst(true)},
-					"null":  {NodeType: Null, AstValue: &NullAst{}},
-					"empty": {NodeType: Object, AstValue: &ObjectAst{map[string]Value{}}},
-					"embed-object": {
+				AstValue: NewObjectAst([]Member{
+					{"str", Value{NodeType: String, AstValue: StringAst("123\b\t\r\n")}},
+					{"num", Value{NodeType: Number, AstValue: NumberAst{Nt: unsignedInteger, u: 123}}},
+					{"bool", Value{NodeType: Bool, AstValue: BoolAst(true)}},
+					{"null", Value{NodeType: Null, AstValue: &NullAst{}}},
+					{"empty", Value{NodeType: Object, AstValue: NewObjectAst()}},
+					{"embed-object", Value{
 						NodeType: Object,
-						AstValue: &ObjectAst{map[string]Value{
-							"hello": {NodeType: String, AstValue: StringAst("world")},
-						}}},
-					"array-in-object": {
+						AstValue: NewObjectAst([]Member{
+							{"hello", Value{NodeType: String, AstValue: StringAst("world")}},
+						}...)}},
+					{"array-in-object", Value{
 						NodeType: Object,
-						AstValue: &ObjectAst{map[string]Value{
-							"hello": {
+						AstValue: NewObjectAst([]Member{
+							{"hello", Value{
 								NodeType: Array,
 								AstValue: &ArrayAst{Values: []Value{{NodeType: String, AstValue: StringAst("world")}}}}},
-						}},
-					"array": {
+						}...)}},
+					{"array", Value{
 						NodeType: Array,
 						AstValue: &ArrayAst{[]Value{
 							{NodeType: String, AstValue: StringAst("world")},
 						}},
-					},
-					"empty-array": {
+					}},
+					{"empty-array", Value{
 						NodeType: Array,
 						AstValue: &ArrayAst{},
-					},
-					"embed-empty-array": {
+					}},
+					{"embed-empty-array", Value{
 						NodeType: Array,
 						AstValue: &ArrayAst{[]Value{
 							{NodeType: Array, AstValue: &ArrayAst{}},
 							{NodeType: Array, AstValue: &ArrayAst{}},
 						}},
-					},
-					"array-empty-obj": {
+					}},
+					{"array-empty-obj", Value{
 						NodeType: Array,
 						AstValue: &ArrayAst{[]Value{
-							{NodeType: Object, AstValue: &ObjectAst{KvMap: map[string]Value{}}},
-							{NodeType: Object, AstValue: &ObjectAst{KvMap: map[string]Value{}}},
+							{NodeType: Object, AstValue: NewObjectAst()},
+							{NodeType: Object, AstValue: NewObjectAst()},
 						}},
-					},
-					"array-obj": {
+					}},
+					{"array-obj", Value{
 						NodeType: Array,
 						AstValue: &ArrayAst{[]Value{
-							{NodeType: Object, AstValue: &ObjectAst{map[string]Value{"hello": {NodeType: String, AstValue: StringAst("world")}}}},
-							{NodeType: Object, AstValue: &ObjectAst{map[string]Value{"hello": {NodeType: String, AstValue: StringAst("world")}}}},
+							{NodeType: Object, AstValue: NewObjectAst([]Member{{"hello", Value{NodeType: String, AstValue: StringAst("world")}}}...)},
+							{NodeType: Object, AstValue: NewObjectAst([]Member{{"hello", Value{NodeType: String, AstValue: StringAst("world")}}}...)},
 						}},
-					},
-				}},
+					}},
+				}...),
 			},
 		},
 	}
@@ -371,3 +383,438 @@ func Test_Parse_Mixture(t *testing.T) {
 	}
 
 }
+
+func Test_ParseWithError(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		err      error
+		position Position
+		token    Type
+	}{
+		"unclosed array":          {input: `[1, 2`, err: ErrUnexpectedEOF, position: Position{Offset: 5, Line: 1, Column: 6}, token: TokenEOF},
+		"unclosed object":         {input: `{"a": 1`, err: ErrUnexpectedEOF, position: Position{Offset: 7, Line: 1, Column: 8}, token: TokenEOF},
+		"object without value":    {input: `{"a": }`, err: ErrUnexpectedToken, position: Position{Offset: 6, Line: 1, Column: 7}, token: TokenObjectEnd},
+		"object without colon":    {input: `{"a" 1}`, err: ErrUnexpectedToken, position: Position{Offset: 5, Line: 1, Column: 6}, token: TokenNumber},
+		"object with number key":  {input: `{1: 1}`, err: ErrUnexpectedToken, position: Position{Offset: 1, Line: 1, Column: 2}, token: TokenNumber},
+		"object trailing comma":   {input: `{"a": 1,}`, err: ErrUnexpectedToken, position: Position{Offset: 8, Line: 1, Column: 9}, token: TokenObjectEnd},
+		"array trailing comma":    {input: "[1,\n]", err: ErrUnexpectedToken, position: Position{Offset: 4, Line: 2, Column: 1}, token: TokenArrayEnd},
+		"array missing comma":     {input: `[1 2]`, err: ErrUnexpectedToken, position: Position{Offset: 3, Line: 1, Column: 4}, token: TokenNumber},
+		"duplicated key":          {input: "{\n\"a\": 1,\n\"a\": 2}", err: ErrDuplicatedKey, position: Position{Offset: 10, Line: 3, Column: 1}, token: TokenString},
+		"inconsistent array":      {input: `[1, "a"]`, err: ErrInconsistentArray, position: Position{Offset: 4, Line: 1, Column: 5}, token: TokenString},
+		"inconsistent multiline":  {input: "[1,\n {\n\"a\": 1}]", err: ErrInconsistentArray, position: Position{Offset: 5, Line: 2, Column: 2}, token: TokenObjectStart},
+		"invalid literal":         {input: `[nil]`, err: ErrInvalidLiteral, position: Position{Offset: 1, Line: 1, Column: 2}, token: TokenInvalid},
+		"unexpected closing":      {input: `]`, err: ErrUnexpectedToken, position: Position{Offset: 0, Line: 1, Column: 1}, token: TokenArrayEnd},
+		"invalid character":       {input: `{"a": x}`, err: ErrInvalidCharacter, position: Position{Offset: 6, Line: 1, Column: 7}, token: TokenInvalid},
+		"invalid top-level char":  {input: `@`, err: ErrInvalidCharacter, position: Position{Offset: 0, Line: 1, Column: 1}, token: TokenInvalid},
+		"invalid nested string":   {input: `[["\x"]]`, err: ErrInvalidString, position: Position{Offset: 4, Line: 1, Column: 5}, token: TokenString},
+		"unexpected colon in arr": {input: `[:]`, err: ErrUnexpectedToken, position: Position{Offset: 1, Line: 1, Column: 2}, token: TokenColon},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			val, err := NewParser([]byte(tc.input), WithHomogeneousArrays()).ParseWithError()
+			assert.Nil(t, val)
+			assert.ErrorIs(t, err, tc.err)
+
+			var pe *ParseError
+			if assert.ErrorAs(t, err, &pe) {
+				assert.Equal(t, tc.position, pe.Position)
+				assert.Equal(t, tc.token, pe.Token)
+			}
+			assert.Panics(t, func() { Parse([]byte(tc.input), WithHomogeneousArrays()) })
+		})
+	}
+}
+
+func Test_ParseError_Message(t *testing.T) {
+	testCases := map[string]struct {
+		input string
+		msg   string
+	}{
+		"end of input":       {input: `[1,`, msg: "unexpected end of input at line 1, column 4 (offset 3): got end of input, expected a json value"},
+		"invalid character":  {input: `{"a": @}`, msg: "invalid character '@' at line 1, column 7 (offset 6)"},
+		"non-ascii":          {input: `[é]`, msg: "invalid character 0xc3 at line 1, column 2 (offset 1)"},
+		"invalid literal":    {input: `nul`, msg: "unexpected end of input at line 1, column 4 (offset 3): got end of input, expected null"},
+		"misspelled literal": {input: `[ture]`, msg: "invalid literal at line 1, column 2 (offset 1): got invalid token, expected true or false"},
+		"inconsistent array": {input: `[1, {}]`, msg: "inconsistent array value type at line 1, column 5 (offset 4): got '{', expected number like the first element"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewParser([]byte(tc.input), WithHomogeneousArrays()).ParseWithError()
+			assert.EqualError(t, err, tc.msg)
+		})
+	}
+}
+
+func Test_Parse_Limits(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		opt      ParserOption
+		err      error
+		position Position
+	}{
+		"depth at limit":        {input: `[{"a": []}]`, opt: WithMaxDepth(3)},
+		"too deep":              {input: `[{"a": [[]]}]`, opt: WithMaxDepth(3), err: ErrMaxDepth, position: Position{Offset: 8, Line: 1, Column: 9}},
+		"too deep literal":      {input: `[1]`, opt: WithMaxDepth(0)},
+		"size at limit":         {input: `[1, 2]`, opt: WithMaxDocumentSize(6)},
+		"too large":             {input: `[1, 2, 3]`, opt: WithMaxDocumentSize(6), err: ErrMaxDocumentSize, position: Position{Offset: 6, Line: 1, Column: 7}},
+		"too large string":      {input: `"abcdef"`, opt: WithMaxDocumentSize(4), err: ErrMaxDocumentSize, position: Position{Offset: 4, Line: 1, Column: 5}},
+		"string at limit":       {input: `"\u4f60"`, opt: WithMaxStringLength(3)},
+		"too long string":       {input: `["abc", "abcd"]`, opt: WithMaxStringLength(3), err: ErrMaxStringLength, position: Position{Offset: 8, Line: 1, Column: 9}},
+		"too long key":          {input: `{"abcd": 1}`, opt: WithMaxStringLength(3), err: ErrMaxStringLength, position: Position{Offset: 1, Line: 1, Column: 2}},
+		"object keys at limit":  {input: `{"a": 1, "b": {"c": 2, "d": 3}}`, opt: WithMaxObjectKeys(2)},
+		"too many keys":         {input: `{"a": 1, "b": 2, "c": 3}`, opt: WithMaxObjectKeys(2), err: ErrMaxObjectKeys, position: Position{Offset: 17, Line: 1, Column: 18}},
+		"array length at limit": {input: `[[1, 2], [3]]`, opt: WithMaxArrayLength(2)},
+		"too long array":        {input: `[1, [2, 3, 4]]`, opt: WithMaxArrayLength(2), err: ErrMaxArrayLength, position: Position{Offset: 11, Line: 1, Column: 12}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewParser([]byte(tc.input), tc.opt).ParseWithError()
+			_, streamErr := NewStreamParser(iotest.OneByteReader(strings.NewReader(tc.input)), tc.opt).ParseWithError()
+			assert.Equal(t, err, streamErr)
+			if tc.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, tc.err)
+			var pe *ParseError
+			if assert.ErrorAs(t, err, &pe) {
+				assert.Equal(t, tc.position, pe.Position)
+			}
+		})
+	}
+
+	// all bytes read are counted including the ones after the top-level value
+	for _, input := range []string{`{"a":1}      `, "{\"a\":1}\n{}"} {
+		_, err := NewParser([]byte(input), WithMaxDocumentSize(7)).ParseWithError()
+		assert.ErrorIs(t, err, ErrMaxDocumentSize)
+		_, err = NewStreamParser(strings.NewReader(input), WithMaxDocumentSize(7)).ParseWithError()
+		assert.ErrorIs(t, err, ErrMaxDocumentSize)
+		_, err = NewStreamParser(iotest.OneByteReader(strings.NewReader(input)), WithMaxDocumentSize(7), WithSingleDocument()).ParseWithError()
+		assert.ErrorIs(t, err, ErrMaxDocumentSize)
+	}
+	_, err := NewParser([]byte("{\"a\":1}\n{}"), WithMaxDocumentSize(9)).ParseWithError()
+	var pe *ParseError
+	if assert.ErrorAs(t, err, &pe) {
+		assert.Equal(t, Position{Offset: 9, Line: 2, Column: 2}, pe.Position)
+	}
+
+	// the deeply nested input is rejected before exhausting the stack
+	deep := strings.Repeat("[", 1000000)
+	_, err = NewParser([]byte(deep), WithMaxDepth(100)).ParseWithError()
+	assert.ErrorIs(t, err, ErrMaxDepth)
+}
+
+func Test_ParseBytes(t *testing.T) {
+	val, err := ParseBytes([]byte(`{"a": [1]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, Object, val.NodeType)
+
+	val, err = ParseBytes([]byte(" \n "))
+	assert.NoError(t, err)
+	assert.Nil(t, val)
+
+	val, err = NewParser([]byte(`[1,`)).ParseE().Decompose()
+	assert.Nil(t, val)
+	assert.EqualError(t, err, "unexpected end of input at line 1, column 4 (offset 3): got end of input, expected a json value")
+}
+
+func Test_Parse_Spans(t *testing.T) {
+	input := "{\n  \"a\": [1, 2222],\n  \"b\": {\"c\": null}\n}"
+	val, err := NewParser([]byte(input), WithSpans()).ParseWithError()
+	assert.NoError(t, err)
+
+	span := func(start, end Position) *Span {
+		return &Span{Start: start, End: end}
+	}
+	assert.Equal(t, span(Position{0, 1, 1}, Position{len(input), 4, 2}), val.Span)
+
+	obj := val.AstValue.(*ObjectAst)
+	keySpan, ok := obj.KeySpan("a")
+	assert.True(t, ok)
+	assert.Equal(t, *span(Position{4, 2, 3}, Position{7, 2, 6}), keySpan)
+	_, ok = obj.KeySpan("non-exist")
+	assert.False(t, ok)
+
+	a, _ := obj.Get("a")
+	assert.Equal(t, span(Position{9, 2, 8}, Position{18, 2, 17}), a.Span)
+	elems := GetArrayValues(a)
+	assert.Equal(t, span(Position{10, 2, 9}, Position{11, 2, 10}), elems[0].Span)
+	assert.Equal(t, span(Position{13, 2, 12}, Position{17, 2, 16}), elems[1].Span)
+
+	b, _ := obj.Get("b")
+	assert.Equal(t, span(Position{27, 3, 8}, Position{38, 3, 19}), b.Span)
+	c := GetObjectKvMap(b)["c"]
+	assert.Equal(t, span(Position{33, 3, 14}, Position{37, 3, 18}), c.Span)
+	assert.Equal(t, "3:14-3:18", c.Span.String())
+
+	// spans are not recorded by default
+	val = Parse([]byte(input))
+	assert.Nil(t, val.Span)
+	_, ok = val.AstValue.(*ObjectAst).KeySpan("a")
+	assert.False(t, ok)
+}
+
+func Test_StreamParser(t *testing.T) {
+	inputs := []string{
+		``,
+		`"123"`,
+		`-0.99`,
+		`true`,
+		`null`,
+		`[1, "a", null, [true], {}]`,
+		`{"str": "a\"b\\c你", "num": [1, 2.5e3, -3], "obj": {"null": null, "bool": false}}`,
+		"\n\t{ \"a\" :\r\n [ ] }  ",
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			expected, err := NewParser([]byte(input), WithSpans()).ParseWithError()
+			assert.NoError(t, err)
+
+			// read one byte each time to make the buffer refilled as many as possible
+			val, err := NewStreamParser(iotest.OneByteReader(strings.NewReader(input)), WithSpans()).ParseWithError()
+			assert.NoError(t, err)
+			assert.Equal(t, expected, val)
+
+			val, err = NewStreamParser(strings.NewReader(input)).ParseWithError()
+			assert.NoError(t, err)
+			assert.Equal(t, Parse([]byte(input)), val)
+		})
+	}
+}
+
+func Test_StreamParser_Error(t *testing.T) {
+	inputs := []string{
+		`[1, 2`,
+		`{"a": tru}`,
+		`"\u12`,
+		"{\n\"a\": 1,\n\"a\": 2}",
+		`[1 2]`,
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			_, expected := ParseBytes([]byte(input))
+			assert.Error(t, expected)
+
+			_, err := NewStreamParser(iotest.OneByteReader(strings.NewReader(input))).ParseWithError()
+			assert.Equal(t, expected, err)
+		})
+	}
+
+	// the error of reader is returned as it is
+	readErr := errors.New("network is down")
+	r := io.MultiReader(strings.NewReader(`{"a": [1, 2`), iotest.ErrReader(readErr))
+	val, err := NewStreamParser(r).ParseWithError()
+	assert.Nil(t, val)
+	assert.Equal(t, readErr, err)
+}
+
+func Test_Parse_Truncated(t *testing.T) {
+	input := `{"s": "a\"\u4f60", "n": [-1.5e+3, 0, true, false, null], "o": {}}`
+	// every truncated input ends in the middle of the top-level object, the empty one is valid
+	for i := 1; i < len(input); i++ {
+		truncated := input[:i]
+		t.Run(truncated, func(t *testing.T) {
+			_, err := ParseBytes([]byte(truncated))
+			assert.ErrorIs(t, err, ErrUnexpectedEOF)
+
+			var pe *ParseError
+			if assert.ErrorAs(t, err, &pe) {
+				assert.Equal(t, i, pe.Offset)
+				assert.Equal(t, TokenEOF, pe.Token)
+			}
+
+			_, streamErr := NewStreamParser(iotest.OneByteReader(strings.NewReader(truncated))).ParseWithError()
+			assert.Equal(t, err, streamErr)
+		})
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		``,
+		`{"a": tru`,
+		`[1, 2.5e-3, -0, "\u12`,
+		`{"s": "a\"\u4f60", "n": [1, null, true], "o": {"x": {}}}`,
+		`"\ud83d\ude00"`,
+		`1e400`,
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		val, err := ParseBytes(data)
+		if err != nil {
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("error %v isn't a ParseError", err)
+			}
+			if pe.Offset < 0 || pe.Offset > len(data) {
+				t.Fatalf("error offset %d is out of input length %d", pe.Offset, len(data))
+			}
+			return
+		}
+		if val == nil {
+			// the input is empty or whitespace only
+			return
+		}
+
+		// a valid document is kept after marshaling and parsing again
+		bs, err := Marshal(val)
+		if err != nil {
+			t.Fatalf("fail to marshal %q: %v", data, err)
+		}
+		again, err := ParseBytes(bs)
+		if err != nil {
+			t.Fatalf("fail to parse marshaled %q: %v", bs, err)
+		}
+		if !Equal(val, again) {
+			t.Fatalf("%q is changed to %q after round trip", data, bs)
+		}
+	})
+}
+
+func Test_StreamParser_LargeInput(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := 0; i < 100000; i++ {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(`{"id": 1234567, "name": "helloworld"}`)
+	}
+	sb.WriteString("]")
+
+	p := NewStreamParser(strings.NewReader(sb.String()))
+	val, err := p.ParseWithError()
+	assert.NoError(t, err)
+	assert.Len(t, GetArrayValues(val), 100000)
+
+	// the buffer only keeps the current token instead of the whole input
+	assert.Equal(t, defaultBufferSize, cap(p.l.bs))
+	assert.Nil(t, p.Raw(Span{End: Position{Offset: 1}}))
+
+	// a long token grows the buffer
+	long := `"` + strings.Repeat("a", 3*defaultBufferSize) + `"`
+	val, err = NewStreamParser(strings.NewReader(long)).ParseWithError()
+	assert.NoError(t, err)
+	assert.Equal(t, long[1:len(long)-1], GetString(val))
+}
+
+func Test_Parser_Next(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		expected []string
+	}{
+		"empty":           {input: "", expected: nil},
+		"whitespace only": {input: " \n\t ", expected: nil},
+		"single":          {input: `{"a": 1}`, expected: []string{`{"a":1}`}},
+		"ndjson":          {input: "{\"a\": 1}\n{\"b\": [2]}\n\n3\n", expected: []string{`{"a":1}`, `{"b":[2]}`, `3`}},
+		"concatenated":    {input: `{"a":1}{"b":2}[]"str"null`, expected: []string{`{"a":1}`, `{"b":2}`, `[]`, `"str"`, `null`}},
+		"space separated": {input: `1 true -2.5`, expected: []string{`1`, `true`, `-2.5`}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			parsers := []*Parser{
+				NewParser([]byte(tc.input)),
+				NewStreamParser(iotest.OneByteReader(strings.NewReader(tc.input))),
+			}
+			for _, p := range parsers {
+				var actual []string
+				for {
+					val, err := p.Next()
+					if err == io.EOF {
+						break
+					}
+					assert.NoError(t, err)
+					bs, err := Marshal(val)
+					assert.NoError(t, err)
+					actual = append(actual, string(bs))
+				}
+				assert.Equal(t, tc.expected, actual)
+
+				// keep returning io.EOF
+				_, err := p.Next()
+				assert.Equal(t, io.EOF, err)
+			}
+
+			values, err := NewParser([]byte(tc.input)).ParseAll()
+			assert.NoError(t, err)
+			assert.Len(t, values, len(tc.expected))
+		})
+	}
+
+	p := NewParser([]byte("{\"a\": 1}\n{\"a\": 1, }"))
+	_, err := p.Next()
+	assert.NoError(t, err)
+	_, err = p.Next()
+	assert.ErrorIs(t, err, ErrUnexpectedToken)
+
+	values, err := NewParser([]byte("1\n[")).ParseAll()
+	assert.Nil(t, values)
+	assert.ErrorIs(t, err, ErrUnexpectedEOF)
+}
+
+func Test_Parse_SingleDocument(t *testing.T) {
+	// the trailing data is ignored by default
+	val, err := ParseBytes([]byte(`{"a": 1} {"b": 2}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, GetObject(val).Keys())
+
+	val, err = ParseBytes([]byte(" {\"a\": 1} \n\t"), WithSingleDocument())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, GetObject(val).Keys())
+
+	testCases := map[string]struct {
+		input    string
+		err      error
+		position Position
+	}{
+		"another value":     {input: `{"a": 1} {"b": 2}`, err: ErrTrailingData, position: Position{Offset: 9, Line: 1, Column: 10}},
+		"trailing bracket":  {input: "[1]\n]", err: ErrTrailingData, position: Position{Offset: 4, Line: 2, Column: 1}},
+		"trailing garbage":  {input: `true x`, err: ErrInvalidCharacter, position: Position{Offset: 5, Line: 1, Column: 6}},
+		"trailing bad word": {input: `1 nux`, err: ErrInvalidLiteral, position: Position{Offset: 2, Line: 1, Column: 3}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			val, err := NewParser([]byte(tc.input), WithSingleDocument()).ParseWithError()
+			assert.Nil(t, val)
+			assert.ErrorIs(t, err, tc.err)
+			var pe *ParseError
+			if assert.ErrorAs(t, err, &pe) {
+				assert.Equal(t, tc.position, pe.Position)
+			}
+		})
+	}
+}
+
+func Test_Parse_Number(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		expected NumberAst
+	}{
+		"max uint64":      {input: "18446744073709551615", expected: NumberAst{Nt: unsignedInteger, u: 18446744073709551615}},
+		"overflow uint64": {input: "18446744073709551616", expected: NumberAst{Nt: floatNumber, f: 18446744073709551616}},
+		"min int64":       {input: "-9223372036854775808", expected: NumberAst{Nt: integer, i: -9223372036854775808}},
+		"overflow int64":  {input: "-9223372036854775809", expected: NumberAst{Nt: floatNumber, f: -9223372036854775809}},
+		"negative zero":   {input: "-0", expected: NumberAst{Nt: integer}},
+		"exponent":        {input: "1e2", expected: NumberAst{Nt: floatNumber, f: 100}},
+		"underflow":       {input: "1e-400", expected: NumberAst{Nt: floatNumber}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			val, err := ParseBytes([]byte(tc.input))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, GetNumber(val))
+		})
+	}
+
+	for _, input := range []string{"1e400", "[1, -1e309]", "01", `{"a": 1.}`} {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseBytes([]byte(input))
+			assert.ErrorIs(t, err, ErrInvalidNumber)
+			var pe *ParseError
+			assert.ErrorAs(t, err, &pe)
+		})
+	}
+}
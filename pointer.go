@@ -0,0 +1,217 @@
+package astjson
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPointer is reported when a json pointer doesn't follow RFC 6901 syntax.
+	ErrInvalidPointer = errors.New("invalid json pointer")
+	// ErrPointerNotFound is reported when a json pointer refers to a non-existent value.
+	ErrPointerNotFound = errors.New("json pointer not found")
+)
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// Pointer returns the value referred by the json pointer ptr(RFC 6901), for example,
+// "/a/b/0" refers to the first element of key b inside key a. The empty pointer refers
+// to the value itself. The returned pointer refers to the value inside the tree, so changes
+// through it are visible to the tree.
+func (v *Value) Pointer(ptr string) (*Value, error) {
+	tokens, err := parsePointer(ptr)
+	if err != nil {
+		return nil, err
+	}
+	return v.walkPointer(tokens)
+}
+
+// SetPointer replaces the value referred by ptr with val. An object member is created if it
+// doesn't exist, but the array element must exist.
+func (v *Value) SetPointer(ptr string, val Value) error {
+	if ptr == "" {
+		*v = val
+		return nil
+	}
+	return v.modifyPointer(ptr, func(parent *Value, token string) error {
+		switch parent.NodeType {
+		case Object:
+			GetObject(parent).Set(token, val)
+			return nil
+		case Array:
+			values := GetArrayValues(parent)
+			i, err := arrayIndex(token, len(values)-1)
+			if err != nil {
+				return err
+			}
+			values[i] = val
+			return nil
+		}
+		return fmt.Errorf("%w: cannot set %q on %s", ErrPointerNotFound, token, parent.NodeType)
+	})
+}
+
+// AddPointer adds val to where ptr refers to as the "add" operation of json patch(RFC 6902).
+// It sets the object member, or inserts val into an array before the referred index. The index
+// "-" appends val at the end of the array.
+func (v *Value) AddPointer(ptr string, val Value) error {
+	if ptr == "" {
+		*v = val
+		return nil
+	}
+	return v.modifyPointer(ptr, func(parent *Value, token string) error {
+		switch parent.NodeType {
+		case Object:
+			GetObject(parent).Set(token, val)
+			return nil
+		case Array:
+			ar := parent.AstValue.(*ArrayAst)
+			i := len(ar.Values)
+			if token != "-" {
+				var err error
+				if i, err = arrayIndex(token, len(ar.Values)); err != nil {
+					return err
+				}
+			}
+			ar.Values = append(ar.Values, Value{})
+			copy(ar.Values[i+1:], ar.Values[i:])
+			ar.Values[i] = val
+			return nil
+		}
+		return fmt.Errorf("%w: cannot add %q on %s", ErrPointerNotFound, token, parent.NodeType)
+	})
+}
+
+// DeletePointer removes the object member or the array element referred by ptr.
+// The value itself cannot be removed, hence the empty pointer is rejected.
+func (v *Value) DeletePointer(ptr string) error {
+	if ptr == "" {
+		return fmt.Errorf("%w: cannot delete the value itself", ErrInvalidPointer)
+	}
+	return v.modifyPointer(ptr, func(parent *Value, token string) error {
+		switch parent.NodeType {
+		case Object:
+			if !GetObject(parent).Delete(token) {
+				return fmt.Errorf("%w: key %q doesn't exist", ErrPointerNotFound, token)
+			}
+			return nil
+		case Array:
+			ar := parent.AstValue.(*ArrayAst)
+			i, err := arrayIndex(token, len(ar.Values)-1)
+			if err != nil {
+				return err
+			}
+			ar.Values = append(ar.Values[:i], ar.Values[i+1:]...)
+			return nil
+		}
+		return fmt.Errorf("%w: cannot delete %q on %s", ErrPointerNotFound, token, parent.NodeType)
+	})
+}
+
+// modifyPointer finds the parent of the value referred by the non-empty ptr and calls modify
+// with the last reference token.
+func (v *Value) modifyPointer(ptr string, modify func(parent *Value, token string) error) error {
+	tokens, err := parsePointer(ptr)
+	if err != nil {
+		return err
+	}
+
+	last := len(tokens) - 1
+	parent, err := v.walkPointer(tokens[:last])
+	if err != nil {
+		return err
+	}
+	if err = modify(parent, tokens[last]); err != nil {
+		return fmt.Errorf("%s: %w", ptr, err)
+	}
+	return nil
+}
+
+// walkPointer follows the unescaped reference tokens from v.
+func (v *Value) walkPointer(tokens []string) (*Value, error) {
+	cur := v
+	for i, token := range tokens {
+		var err error
+		if cur, err = child(cur, token); err != nil {
+			return nil, fmt.Errorf("%s: %w", formatPointer(tokens[:i+1]), err)
+		}
+	}
+	return cur, nil
+}
+
+// child returns the object member or the array element by the reference token.
+func child(v *Value, token string) (*Value, error) {
+	switch v.NodeType {
+	case Object:
+		val, ok := GetObject(v).Get(token)
+		if !ok {
+			return nil, fmt.Errorf("%w: key %q doesn't exist", ErrPointerNotFound, token)
+		}
+		return val, nil
+	case Array:
+		values := GetArrayValues(v)
+		i, err := arrayIndex(token, len(values)-1)
+		if err != nil {
+			return nil, err
+		}
+		return &values[i], nil
+	}
+	return nil, fmt.Errorf("%w: cannot refer %q on %s", ErrPointerNotFound, token, v.NodeType)
+}
+
+// arrayIndex parses an array index token which should be inside [0, max].
+func arrayIndex(token string, max int) (int, error) {
+	if token == "-" {
+		return 0, fmt.Errorf("%w: index - refers to the nonexistent element after the last one", ErrPointerNotFound)
+	}
+	// leading zeros are not allowed
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPointer, token)
+	}
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPointer, token)
+		}
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i > max {
+		return 0, fmt.Errorf("%w: array index %s out of range", ErrPointerNotFound, token)
+	}
+	return i, nil
+}
+
+// parsePointer splits ptr into the unescaped reference tokens.
+func parsePointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if ptr[0] != '/' {
+		return nil, fmt.Errorf("%w: %q should start with /", ErrInvalidPointer, ptr)
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			// only ~0 and ~1 are valid escape sequences
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("%w: %q contains invalid escape", ErrInvalidPointer, ptr)
+			}
+		}
+		tokens[i] = pointerUnescaper.Replace(token)
+	}
+	return tokens, nil
+}
+
+// formatPointer joins the reference tokens to a json pointer.
+func formatPointer(tokens []string) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		sb.WriteByte('/')
+		sb.WriteString(pointerEscaper.Replace(token))
+	}
+	return sb.String()
+}
@@ -0,0 +1,186 @@
+package astjson
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const pointerJSON = `{
+  "foo": ["bar", "baz"],
+  "": 0,
+  "a/b": 1,
+  "c%d": 2,
+  "e^f": 3,
+  "g|h": 4,
+  "i\\j": 5,
+  "k\"l": 6,
+  " ": 7,
+  "m~n": 8,
+  "nested": {"arr": [{"id": 10}]}
+}`
+
+func Test_Value_Pointer(t *testing.T) {
+	// the examples come from RFC 6901
+	testCases := map[string]string{
+		``:                 `{"foo":["bar","baz"],"":0,"a/b":1,"c%d":2,"e^f":3,"g|h":4,"i\\j":5,"k\"l":6," ":7,"m~n":8,"nested":{"arr":[{"id":10}]}}`,
+		`/foo`:             `["bar","baz"]`,
+		`/foo/0`:           `"bar"`,
+		`/`:                `0`,
+		`/a~1b`:            `1`,
+		`/c%d`:             `2`,
+		`/e^f`:             `3`,
+		`/g|h`:             `4`,
+		`/i\j`:             `5`,
+		`/k"l`:             `6`,
+		`/ `:               `7`,
+		`/m~0n`:            `8`,
+		`/nested/arr/0/id`: `10`,
+	}
+	val := Parse([]byte(pointerJSON))
+	for ptr, expected := range testCases {
+		t.Run(ptr, func(t *testing.T) {
+			actual, err := val.Pointer(ptr)
+			assert.NoError(t, err)
+			bs, err := Marshal(actual)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, string(bs))
+		})
+	}
+}
+
+func Test_Value_Pointer_Error(t *testing.T) {
+	testCases := map[string]struct {
+		ptr    string
+		err    error
+		errStr string
+	}{
+		"no leading slash":    {ptr: `foo`, err: ErrInvalidPointer},
+		"invalid escape":      {ptr: `/m~2n`, err: ErrInvalidPointer},
+		"trailing tilde":      {ptr: `/m~`, err: ErrInvalidPointer},
+		"missing key":         {ptr: `/nested/missing`, err: ErrPointerNotFound, errStr: `/nested/missing: json pointer not found: key "missing" doesn't exist`},
+		"index out of range":  {ptr: `/foo/2`, err: ErrPointerNotFound, errStr: `/foo/2: json pointer not found: array index 2 out of range`},
+		"index past the end":  {ptr: `/foo/-`, err: ErrPointerNotFound},
+		"leading zero":        {ptr: `/foo/01`, err: ErrInvalidPointer},
+		"negative index":      {ptr: `/foo/-1`, err: ErrInvalidPointer},
+		"non numeric index":   {ptr: `/foo/a`, err: ErrInvalidPointer},
+		"literal has no kids": {ptr: `/foo/0/a`, err: ErrPointerNotFound, errStr: `/foo/0/a: json pointer not found: cannot refer "a" on String`},
+	}
+	val := Parse([]byte(pointerJSON))
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual, err := val.Pointer(tc.ptr)
+			assert.Nil(t, actual)
+			assert.ErrorIs(t, err, tc.err)
+			if tc.errStr != "" {
+				assert.EqualError(t, err, tc.errStr)
+			}
+		})
+	}
+}
+
+func Test_Value_ModifyPointer(t *testing.T) {
+	number := func(u uint64) Value {
+		return Value{NodeType: Number, AstValue: NumberAst{Nt: unsignedInteger, u: u}}
+	}
+	testCases := map[string]struct {
+		modify   func(val *Value) error
+		expected string
+	}{
+		"set existing key": {
+			modify:   func(val *Value) error { return val.SetPointer("/a/b", number(9)) },
+			expected: `{"a":{"b":9,"c":[1,2]}}`,
+		},
+		"set new key": {
+			modify:   func(val *Value) error { return val.SetPointer("/a/d~1e", number(9)) },
+			expected: `{"a":{"b":1,"c":[1,2],"d/e":9}}`,
+		},
+		"set array element": {
+			modify:   func(val *Value) error { return val.SetPointer("/a/c/1", number(9)) },
+			expected: `{"a":{"b":1,"c":[1,9]}}`,
+		},
+		"set root": {
+			modify:   func(val *Value) error { return val.SetPointer("", number(9)) },
+			expected: `9`,
+		},
+		"add key": {
+			modify:   func(val *Value) error { return val.AddPointer("/a/d", number(9)) },
+			expected: `{"a":{"b":1,"c":[1,2],"d":9}}`,
+		},
+		"insert array element": {
+			modify:   func(val *Value) error { return val.AddPointer("/a/c/0", number(9)) },
+			expected: `{"a":{"b":1,"c":[9,1,2]}}`,
+		},
+		"insert at the end": {
+			modify:   func(val *Value) error { return val.AddPointer("/a/c/2", number(9)) },
+			expected: `{"a":{"b":1,"c":[1,2,9]}}`,
+		},
+		"append array element": {
+			modify:   func(val *Value) error { return val.AddPointer("/a/c/-", number(9)) },
+			expected: `{"a":{"b":1,"c":[1,2,9]}}`,
+		},
+		"add root": {
+			modify:   func(val *Value) error { return val.AddPointer("", number(9)) },
+			expected: `9`,
+		},
+		"delete key": {
+			modify:   func(val *Value) error { return val.DeletePointer("/a/b") },
+			expected: `{"a":{"c":[1,2]}}`,
+		},
+		"delete array element": {
+			modify:   func(val *Value) error { return val.DeletePointer("/a/c/0") },
+			expected: `{"a":{"b":1,"c":[2]}}`,
+		},
+		"change through pointer": {
+			modify: func(val *Value) error {
+				b, err := val.Pointer("/a/b")
+				*b = number(9)
+				return err
+			},
+			expected: `{"a":{"b":9,"c":[1,2]}}`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			val := Parse([]byte(`{"a": {"b": 1, "c": [1, 2]}}`))
+			assert.NoError(t, tc.modify(val))
+			bs, err := Marshal(val)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, string(bs))
+		})
+	}
+}
+
+func Test_Value_ModifyPointer_Error(t *testing.T) {
+	null := Value{NodeType: Null, AstValue: &NullAst{}}
+	testCases := map[string]struct {
+		modify func(val *Value) error
+		err    error
+	}{
+		"set missing parent":     {modify: func(val *Value) error { return val.SetPointer("/x/y", null) }, err: ErrPointerNotFound},
+		"set out of range":       {modify: func(val *Value) error { return val.SetPointer("/a/c/2", null) }, err: ErrPointerNotFound},
+		"set on literal":         {modify: func(val *Value) error { return val.SetPointer("/a/b/x", null) }, err: ErrPointerNotFound},
+		"set invalid pointer":    {modify: func(val *Value) error { return val.SetPointer("a", null) }, err: ErrInvalidPointer},
+		"add out of range":       {modify: func(val *Value) error { return val.AddPointer("/a/c/3", null) }, err: ErrPointerNotFound},
+		"add invalid index":      {modify: func(val *Value) error { return val.AddPointer("/a/c/x", null) }, err: ErrInvalidPointer},
+		"add on literal":         {modify: func(val *Value) error { return val.AddPointer("/a/b/x", null) }, err: ErrPointerNotFound},
+		"delete root":            {modify: func(val *Value) error { return val.DeletePointer("") }, err: ErrInvalidPointer},
+		"delete missing key":     {modify: func(val *Value) error { return val.DeletePointer("/a/x") }, err: ErrPointerNotFound},
+		"delete out of range":    {modify: func(val *Value) error { return val.DeletePointer("/a/c/2") }, err: ErrPointerNotFound},
+		"delete past the end":    {modify: func(val *Value) error { return val.DeletePointer("/a/c/-") }, err: ErrPointerNotFound},
+		"delete on literal":      {modify: func(val *Value) error { return val.DeletePointer("/a/b/x") }, err: ErrPointerNotFound},
+		"delete invalid pointer": {modify: func(val *Value) error { return val.DeletePointer("/a~") }, err: ErrInvalidPointer},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			input := `{"a":{"b":1,"c":[1,2]}}`
+			val := Parse([]byte(input))
+			assert.ErrorIs(t, tc.modify(val), tc.err)
+
+			// the value is untouched when error happens
+			bs, err := Marshal(val)
+			assert.NoError(t, err)
+			assert.Equal(t, input, string(bs))
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package astjson
+
+import "fmt"
+
+// Position describes a location inside the json document.
+// Offset starts at 0, while Line and Column start at 1 and Column counts bytes.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span describes the range [Start, End) of a Value or an object key inside the json document.
+// It's recorded only when the Parser is created with WithSpans.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%s-%s", s.Start, s.End)
+}
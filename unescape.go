@@ -0,0 +1,117 @@
+package astjson
+
+import (
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Unquote interprets raw as a quoted json string literal and returns the string it represents.
+// The escape sequences are decoded, a lone UTF-16 surrogate and an invalid UTF-8 byte are
+// replaced by U+FFFD as encoding/json does.
+func Unquote(raw []byte) (string, error) {
+	if len(raw) < 2 || raw[0] != '"' || raw[len(raw)-1] != '"' {
+		return "", fmt.Errorf("%w: missing quotes", ErrInvalidString)
+	}
+	return unescape(raw[1 : len(raw)-1])
+}
+
+// unescape decodes the content of a json string literal without the quotes.
+func unescape(bs []byte) (string, error) {
+	// fast path: nothing needs to be decoded
+	plain := true
+	for _, c := range bs {
+		if c == '\\' || c == '"' || c >= utf8.RuneSelf {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return string(bs), nil
+	}
+
+	out := make([]byte, 0, len(bs))
+	for i := 0; i < len(bs); {
+		c := bs[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(bs) {
+				return "", fmt.Errorf("%w: unfinished escape sequence", ErrInvalidString)
+			}
+			switch e := bs[i+1]; e {
+			case '"', '\\', '/':
+				out = append(out, e)
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'u':
+				r, ok := hex4(bs[i+2:])
+				if !ok {
+					return "", fmt.Errorf("%w: invalid unicode escape at %d", ErrInvalidString, i)
+				}
+				i += 6
+				if utf16.IsSurrogate(r) {
+					// a valid surrogate pair is combined into a single rune
+					r2, ok := rune(0), false
+					if i+1 < len(bs) && bs[i] == '\\' && bs[i+1] == 'u' {
+						r2, ok = hex4(bs[i+2:])
+					}
+					if dec := utf16.DecodeRune(r, r2); ok && dec != utf8.RuneError {
+						r = dec
+						i += 6
+					} else {
+						r = utf8.RuneError
+					}
+				}
+				out = utf8.AppendRune(out, r)
+				continue
+			default:
+				return "", fmt.Errorf("%w: invalid escape character %q", ErrInvalidString, e)
+			}
+			i += 2
+		case c == '"':
+			return "", fmt.Errorf("%w: unescaped quote at %d", ErrInvalidString, i)
+		case c < utf8.RuneSelf:
+			out = append(out, c)
+			i++
+		default:
+			r, size := utf8.DecodeRune(bs[i:])
+			if r == utf8.RuneError && size == 1 {
+				out = utf8.AppendRune(out, utf8.RuneError)
+			} else {
+				out = append(out, bs[i:i+size]...)
+			}
+			i += size
+		}
+	}
+	return string(out), nil
+}
+
+// hex4 decodes the leading 4 hex digits of bs.
+func hex4(bs []byte) (rune, bool) {
+	if len(bs) < 4 {
+		return 0, false
+	}
+	var r rune
+	for _, c := range bs[:4] {
+		switch {
+		case '0' <= c && c <= '9':
+			c = c - '0'
+		case 'a' <= c && c <= 'f':
+			c = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, false
+		}
+		r = r*16 + rune(c)
+	}
+	return r, true
+}
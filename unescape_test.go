@@ -0,0 +1,82 @@
+package astjson
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Unquote(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		expected string
+	}{
+		"plain":                  {input: `"hello"`, expected: "hello"},
+		"empty":                  {input: `""`, expected: ""},
+		"simple escapes":         {input: `"\"\\\/\b\f\n\r\t"`, expected: "\"\\/\b\f\n\r\t"},
+		"unicode escape":         {input: `"\u4f60\u597d"`, expected: "\u4f60\u597d"},
+		"raw unicode":            {input: `"你好"`, expected: "你好"},
+		"surrogate pair":         {input: `"\ud83d\ude00"`, expected: "\U0001F600"},
+		"lone high surrogate":    {input: `"\ud83d"`, expected: "\ufffd"},
+		"lone low surrogate":     {input: `"\ude00x"`, expected: "\ufffdx"},
+		"high surrogate and bmp": {input: `"\ud83dA"`, expected: "\ufffdA"},
+		"reversed surrogates":    {input: `"\ude00\ud83d"`, expected: "\ufffd\ufffd"},
+		"invalid utf8":           {input: "\"a\xffb\"", expected: "a\ufffdb"},
+		"escaped zero":           {input: `"\u0000"`, expected: "\x00"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual, err := Unquote([]byte(tc.input))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+
+			// keep the same behavior with encoding/json
+			var expected string
+			assert.NoError(t, json.Unmarshal([]byte(tc.input), &expected))
+			assert.Equal(t, expected, actual)
+
+			val, err := ParseBytes([]byte(tc.input))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, GetString(val))
+		})
+	}
+}
+
+func Test_Unquote_Error(t *testing.T) {
+	testCases := []string{
+		``,
+		`"`,
+		`abc`,
+		`"abc`,
+		`"\"`,
+		`"a"b"`,
+		`"\x"`,
+		`"\u12"`,
+		`"\u12g4"`,
+	}
+	for _, input := range testCases {
+		t.Run(input, func(t *testing.T) {
+			_, err := Unquote([]byte(input))
+			assert.ErrorIs(t, err, ErrInvalidString)
+		})
+	}
+}
+
+func Test_Parser_Raw(t *testing.T) {
+	p := NewParser([]byte(`{"key": ["a\nb", 1.50]}`), WithSpans())
+	val, err := p.ParseWithError()
+	assert.NoError(t, err)
+
+	obj := GetObject(val)
+	assert.Equal(t, []string{"key"}, obj.Keys())
+	keySpan, _ := obj.KeySpan("key")
+	assert.Equal(t, `"key"`, string(p.Raw(keySpan)))
+
+	arr, _ := obj.Get("key")
+	values := GetArrayValues(arr)
+	assert.Equal(t, "a\nb", GetString(&values[0]))
+	assert.Equal(t, `"a\nb"`, string(p.Raw(*values[0].Span)))
+	assert.Equal(t, `1.50`, string(p.Raw(*values[1].Span)))
+	assert.Equal(t, `["a\nb", 1.50]`, string(p.Raw(*arr.Span)))
+}
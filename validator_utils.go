@@ -65,3 +65,20 @@ func ShouldEqualNull() Validator {
 		return nil
 	}
 }
+
+// ShouldBeHomogeneousArray requires the value is an array and all its elements have the same node type.
+func ShouldBeHomogeneousArray() Validator {
+	return func(value *Value) error {
+		if !IsArray(value) {
+			return fmt.Errorf("value should be an array type: %s", value)
+		}
+		values := GetArrayValues(value)
+		for i := range values {
+			if values[i].NodeType != values[0].NodeType {
+				return fmt.Errorf("%w: element %d is %s, expected %s",
+					ErrInconsistentArray, i, values[i].NodeType, values[0].NodeType)
+			}
+		}
+		return nil
+	}
+}
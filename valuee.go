@@ -4,9 +4,10 @@ package astjson
 
 // ParseE is an EXPERIENTIAL function which might be removed in the long run development
 func (p *Parser) ParseE() ValueE {
+	val, err := p.ParseWithError()
 	return ValueE{
-		Value: p.Parse(),
-		e:     nil,
+		Value: val,
+		e:     err,
 	}
 }
 
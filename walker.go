@@ -3,49 +3,170 @@ package astjson
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 var (
 	ErrFieldNotExist = errors.New("field not exist")
+	ErrNilValue      = errors.New("nil value to walk")
+	// ErrDeleteRoot is returned when a Manipulator deletes the walked value itself,
+	// which has no parent to be removed from.
+	ErrDeleteRoot = errors.New("cannot delete the walked value")
+
+	// errEachElement is reported when a scope inside Each is cloned to walk the bound value
+	errEachElement = fmt.Errorf("%w: the scope inside Each refers to every element", ErrNilValue)
 )
 
+// WalkError reports a failure happens at Path during walking.
+type WalkError struct {
+	// Path is the json pointer(RFC 6901) of the value where the error happens,
+	// the empty Path refers to the walked value itself.
+	Path string
+	// Span is the location of the value where the error happens, or its parent if the value
+	// doesn't exist. It's nil unless the value is parsed by the Parser created with WithSpans.
+	Span *Span
+	Err  error
+}
+
+func (e *WalkError) Error() string {
+	if e.Span != nil {
+		loc := fmt.Sprintf("(line %d, column %d)", e.Span.Start.Line, e.Span.Start.Column)
+		if e.Path == "" {
+			return fmt.Sprintf("%s: %s", loc, e.Err)
+		}
+		return fmt.Sprintf("%s %s: %s", e.Path, loc, e.Err)
+	}
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+func (e *WalkError) Unwrap() error {
+	return e.Err
+}
+
+// WalkErrors is all the errors found by a walker in the aggregate mode, in the walking order.
+// On each layer, the failure of the value itself comes first, then the missing fields, the
+// optional keys and the validated keys, each kind in its registering order, and at last the
+// failures inside the paths in their registering order.
+type WalkErrors []*WalkError
+
+func (e WalkErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is reports whether any error inside matches target, which helps errors.Is to check
+// the errors inside on every supported go version.
+func (e WalkErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error inside that matches target, which helps errors.As to check
+// the errors inside on every supported go version.
+func (e WalkErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
 // todo: think about is it possible to embed those features into AST parser directly
 // By this way, during parsing, we could parse it directly instead of hindsight.
 
 // Validator is used in Walker and validates a Value according to the logic you need.
-// Validator will be executed before Manipulator.
+// Validator will be executed before Manipulator, it always sees the value as it's given.
 type Validator func(value *Value) error
 
 // Manipulator allows to change the Value and its children KvMap.
-// Manipulator will be executed after Validator.
+// Manipulator will be executed only after all Validators of the walk pass, so a failed
+// walk never changes the value.
+// It could rewrite the Value in place, replace it by assigning *value, or remove it
+// from its parent by DeleteValue.
 type Manipulator func(value *Value)
 
+// deletedAst is the AstValue of a Value marked by DeleteValue.
+type deletedAst struct{}
+
+// DeleteValue marks value to be removed from its parent object or array, it should be
+// called inside a Manipulator only. The marked values are removed after all manipulators
+// are executed, so the indexes of array elements stay the same during walking.
+// The walked value itself cannot be deleted, see ErrDeleteRoot.
+func DeleteValue(value *Value) {
+	value.AstValue = deletedAst{}
+}
+
+func isDeleted(value *Value) bool {
+	_, ok := value.AstValue.(deletedAst)
+	return ok
+}
+
+// Walker registers the handlers to walk a Value. The registered handlers and paths
+// form a plan which could be walked against many values by WalkValue, even concurrently
+// as long as the handlers are safe for concurrent use.
 type Walker struct {
 	// head marks the head of the walker
 	head *Walker
-	next *Walker
+	// parent is the walker where Path creates current walker, and children are
+	// the walkers created by Path on current walker in order
+	parent   *Walker
+	children []*Walker
 
-	value *Value
-	err   error
+	// keys is the path from the value of parent to the value of current walker
+	keys []string
+	// each reports current walker walks every element of the array referred by keys
+	each bool
+	// value is the value bound by NewWalker, it's nil for a walker used as a plan only.
+	// valueErr is the error of resolving the value of a path scope from the bound value,
+	// it's reported only when the walker is walked from as the very beginning by Clone.
+	value    *Value
+	valueErr error
+	// err is the error of registering current walker, such as an invalid path, which
+	// is reported whenever current walker is walked
+	err error
+	// aggregate is only meaningful for the head walker, see Aggregate
+	aggregate bool
 
 	// field stores the current field for possible validator
 	field              string
 	compulsoryFields   []string
-	validators         map[string]Validator
-	optionalValidators map[string]Validator
+	validators         []keyValidator
+	optionalValidators []keyValidator
 
-	// literalValidator is registered by Validate when compulsoryFields is emtpy
+	// literalValidator is registered by Validate when compulsoryFields is emtpy,
+	// it validates the value of current layer whatever its node type is
 	literalValidator Validator
+
+	// manipulators are executed in the registering order after validators pass
+	manipulators []keyManipulator
 }
 
+// NewWalker creates a walker on value, the value could be nil if the walker is used
+// as a plan by WalkValue only.
 func NewWalker(value *Value) *Walker {
-	var w Walker
-	w.value = value
-	w.head = &w
-	w.validators = make(map[string]Validator)
-	w.optionalValidators = make(map[string]Validator)
-	w.compulsoryFields = make([]string, 0, 5)
-	return &w
+	w := newWalker(value)
+	w.head = w
+	return w
+}
+
+func newWalker(value *Value) *Walker {
+	return &Walker{
+		value:            value,
+		compulsoryFields: make([]string, 0, 5),
+	}
 }
 
 // Field requires field is compulsory during walking on current layer.
@@ -56,9 +177,8 @@ func (w *Walker) Field(field string) *Walker {
 }
 
 // Validate validates a key's value last registered be Field,
-// or a literal or an array on current layer.
+// or the value on current layer if no field is submitted before, including an object.
 // It will end up walking ast Value when the validator returns a non-nil error.
-// It panics when no field is submitted before.
 // It overrides the old one if multiple validators are submitted
 func (w *Walker) Validate(validator Validator) *Walker {
 	l := len(w.compulsoryFields)
@@ -66,7 +186,7 @@ func (w *Walker) Validate(validator Validator) *Walker {
 		w.literalValidator = validator
 		return w
 	}
-	w.validators[w.field] = validator
+	w.validators = setValidator(w.validators, w.field, validator)
 	return w
 }
 
@@ -79,112 +199,495 @@ func (w *Walker) ValidateKey(key string, validator Validator) *Walker {
 // Optional marks a key is optional alongside a validator on current layer.
 // The validator is respect only if the key exit.
 func (w *Walker) Optional(key string, validator Validator) *Walker {
-	w.optionalValidators[key] = validator
+	w.optionalValidators = setValidator(w.optionalValidators, key, validator)
+	return w
+}
+
+// Manipulate manipulates a key's value last registered be Field,
+// or the value on current layer if no field is submitted before.
+// The manipulators are executed after all validators of the walk pass, layer by layer in
+// the registering order.
+func (w *Walker) Manipulate(manipulator Manipulator) *Walker {
+	if len(w.compulsoryFields) == 0 {
+		w.manipulators = append(w.manipulators, keyManipulator{self: true, manipulator: manipulator})
+		return w
+	}
+	return w.ManipulateKey(w.field, manipulator)
+}
+
+// ManipulateKey manipulates the value of key on current layer, unlike ValidateKey the key
+// isn't required. If the key doesn't exist, the manipulator receives a Value whose AstValue
+// is nil, and the Value is added to the object if the manipulator sets it, which helps to
+// set a default value.
+func (w *Walker) ManipulateKey(key string, manipulator Manipulator) *Walker {
+	w.manipulators = append(w.manipulators, keyManipulator{key: key, manipulator: manipulator})
+	return w
+}
+
+// keyManipulator is a manipulator of key, or the value on current layer if self is true.
+type keyManipulator struct {
+	key         string
+	self        bool
+	manipulator Manipulator
+}
+
+// keyValidator is a validator of key, validators are kept in a slice to execute them
+// in the registering order.
+type keyValidator struct {
+	key       string
+	validator Validator
+}
+
+// setValidator overrides the validator of key in place, or appends it if key is new.
+func setValidator(validators []keyValidator, key string, validator Validator) []keyValidator {
+	for i := range validators {
+		if validators[i].key == key {
+			validators[i].validator = validator
+			return validators
+		}
+	}
+	return append(validators, keyValidator{key: key, validator: validator})
+}
+
+// Aggregate makes Walk and WalkValue run all the validators on all paths instead of
+// stopping at the first failure. A path which cannot be walked skips its own scope only.
+// The failures are reported by WalkErrors in the walking order, each failure is a *WalkError
+// with the path of the failed value.
+func (w *Walker) Aggregate() *Walker {
+	w.head.aggregate = true
 	return w
 }
 
 // Walk executes all handlers submitted to it and the sub-walker created by Path and EndPath.
 // The returned value always be the value in original walker. See Clone to walk from current walker
 // as the very beginning.
-// The error is returned when validator fails, and the value is nil.
+// A *WalkError with the path and the location of the failed value is returned when a validator
+// fails, and the value is nil.
 func (w *Walker) Walk() (*Value, error) {
-	current := w.head
-	for current != nil {
-		if _, err := current.walk(); err != nil {
-			return nil, err
-		}
-		current = current.next
+	if w.head.valueErr != nil {
+		return nil, w.head.valueErr
 	}
-	return w.head.value, nil
+	return w.WalkValue(w.head.value)
 }
 
-// Clone return a new path which removes the relationships with its ancestor
-func (w *Walker) Clone() *Walker {
-	// todo: implement me!
-	return nil
+// WalkValue walks value with all handlers and paths registered in the walker from its head,
+// as Walk does for the value bound by NewWalker. The walker isn't changed during walking,
+// so a walker could be defined once and walk many values concurrently.
+// The manipulators are executed only if all validators pass. If a Manipulator deletes value
+// itself, ErrDeleteRoot is returned and value is reset to the one before that Manipulator.
+func (w *Walker) WalkValue(value *Value) (*Value, error) {
+	// the head cloned from the scope of Each walks every element of value
+	state := walkState{aggregate: w.head.aggregate}
+	if w.head.each {
+		w.head.walkEach(value, nil, &state)
+	} else {
+		w.head.walkTree(value, nil, &state)
+	}
+	if err := state.error(); err != nil {
+		return nil, err
+	}
+
+	var deletions [][]string
+	if w.head.each {
+		w.head.manipulateEach(value, nil, &deletions)
+	} else {
+		if deleted, before := w.head.manipulate(value); deleted {
+			*value = before
+			return nil, ErrDeleteRoot
+		}
+		w.head.manipulateChildren(value, nil, &deletions)
+	}
+	removeDeleted(value, deletions)
+	return value, nil
 }
 
-// walk executes all handlers submitted to it and return a final Value after walking.
-// The error is returned when validator fails, and the value is nil.
-func (w *Walker) walk() (*Value, error) {
+// walkTree validates value by current walker and then the sub-walkers in the registering order,
+// path is the keys from the value walked by head to value. It reports whether walking should stop.
+func (w *Walker) walkTree(value *Value, path []string, state *walkState) bool {
+	ptr := formatPointer(path)
+	// the registration error skips current scope only in the aggregate mode
 	if w.err != nil {
-		return nil, w.err
+		return state.report(ptr, value, w.err)
+	}
+	if w.walk(value, ptr, state) {
+		return true
+	}
+
+	for _, child := range w.children {
+		childPath := append(path[:len(path):len(path)], child.keys...)
+		val, n, err := walkPath(value, child.keys)
+		if err != nil {
+			// the location of the deepest existing value is reported
+			parent, _, _ := walkPath(value, child.keys[:n])
+			if state.report(formatPointer(childPath[:len(path)+n+1]), parent, err) {
+				return true
+			}
+			continue
+		}
+		if child.each {
+			if child.walkEach(val, childPath, state) {
+				return true
+			}
+			continue
+		}
+		if child.walkTree(val, childPath, state) {
+			return true
+		}
+	}
+	return false
+}
+
+// walkEach validates every element of the array value by current walker.
+// It reports whether walking should stop.
+func (w *Walker) walkEach(value *Value, path []string, state *walkState) bool {
+	if value == nil || value.NodeType != Array {
+		err := ErrNilValue
+		if value != nil {
+			err = fmt.Errorf("cannot walk each element of nodeype %s", value.NodeType)
+		}
+		return state.report(formatPointer(path), value, err)
+	}
+	ar := value.AstValue.(*ArrayAst)
+	for i := range ar.Values {
+		if w.walkTree(&ar.Values[i], append(path[:len(path):len(path)], strconv.Itoa(i)), state) {
+			return true
+		}
+	}
+	return false
+}
+
+// manipulateTree executes the manipulators on value by current walker and then the sub-walkers
+// in the registering order, the sub-walkers of a deleted value are skipped.
+// It reports whether value is deleted.
+func (w *Walker) manipulateTree(value *Value, path []string, deletions *[][]string) bool {
+	if deleted, _ := w.manipulate(value); deleted {
+		return true
 	}
-	nodeTyp := w.value.NodeType
-	switch nodeTyp {
-	case String, Number, Bool, Null, Array:
-		return w.checkArrayAndLiteral()
+	w.manipulateChildren(value, path, deletions)
+	return false
+}
 
+// manipulateChildren executes the sub-walkers on value in the registering order. The paths of
+// deleted values are appended to deletions instead of removing the values at once.
+func (w *Walker) manipulateChildren(value *Value, path []string, deletions *[][]string) {
+	for _, child := range w.children {
+		// the manipulators might change the value, the paths don't exist anymore are skipped
+		val, _, err := walkPath(value, child.keys)
+		if err != nil || isDeleted(val) {
+			continue
+		}
+		childPath := append(path[:len(path):len(path)], child.keys...)
+		if child.each {
+			child.manipulateEach(val, childPath, deletions)
+			continue
+		}
+		if child.manipulateTree(val, childPath, deletions) {
+			*deletions = append(*deletions, childPath)
+		}
+	}
+}
+
+// manipulateEach executes the manipulators on every element of the array value by current walker.
+func (w *Walker) manipulateEach(value *Value, path []string, deletions *[][]string) {
+	ar, ok := value.AstValue.(*ArrayAst)
+	if !ok {
+		return
+	}
+	for i := range ar.Values {
+		elemPath := append(path[:len(path):len(path)], strconv.Itoa(i))
+		if w.manipulateTree(&ar.Values[i], elemPath, deletions) {
+			*deletions = append(*deletions, elemPath)
+		}
+	}
+}
+
+// removeDeleted removes the deleted values at paths from value. The deeper paths are removed
+// first and the array elements are removed from the last one, so removing a value never
+// moves the others not removed yet.
+func removeDeleted(value *Value, paths [][]string) {
+	sort.SliceStable(paths, func(i, j int) bool {
+		if len(paths[i]) != len(paths[j]) {
+			return len(paths[i]) > len(paths[j])
+		}
+		// the array indexes come first in the descending order
+		last := len(paths[i]) - 1
+		x, errX := strconv.Atoi(paths[i][last])
+		y, errY := strconv.Atoi(paths[j][last])
+		if errX != nil || errY != nil {
+			return errX == nil && errY != nil
+		}
+		return x > y
+	})
+	for _, path := range paths {
+		last := len(path) - 1
+		parent, _, err := walkPath(value, path[:last])
+		if err != nil || isDeleted(parent) {
+			continue
+		}
+		deleteChild(parent, path[last])
+	}
+}
+
+// deleteChild removes the member key of an object, or the element at index key of an array.
+func deleteChild(parent *Value, key string) {
+	switch parent.NodeType {
 	case Object:
-		return w.checkObject()
+		GetObject(parent).Delete(key)
+	case Array:
+		ar := parent.AstValue.(*ArrayAst)
+		if i, err := arrayIndex(key, len(ar.Values)-1); err == nil {
+			ar.Values = append(ar.Values[:i], ar.Values[i+1:]...)
+		}
+	}
+}
+
+// manipulate executes the manipulators on value, it reports whether value is deleted and
+// the value before the Manipulator deleting it.
+func (w *Walker) manipulate(value *Value) (bool, Value) {
+	for _, km := range w.manipulators {
+		if km.self {
+			before := *value
+			if km.manipulator(value); isDeleted(value) {
+				return true, before
+			}
+			continue
+		}
+		if value.NodeType != Object {
+			continue
+		}
+		obj := GetObject(value)
+		val, ok := obj.Get(km.key)
+		if !ok {
+			var created Value
+			if km.manipulator(&created); created.AstValue != nil && !isDeleted(&created) {
+				obj.Set(km.key, created)
+			}
+			continue
+		}
+		if km.manipulator(val); isDeleted(val) {
+			obj.Delete(km.key)
+		}
+	}
+	return false, Value{}
+}
+
+// walkState collects the errors of walking a value.
+type walkState struct {
+	aggregate bool
+	errs      WalkErrors
+}
+
+// report records err happens on value at ptr, it returns true if walking should stop.
+func (s *walkState) report(ptr string, value *Value, err error) bool {
+	walkErr := &WalkError{Path: ptr, Err: err}
+	if value != nil {
+		walkErr.Span = value.Span
+	}
+	s.errs = append(s.errs, walkErr)
+	return !s.aggregate
+}
+
+// error returns the first error, or all errors in the aggregate mode.
+func (s *walkState) error() error {
+	if len(s.errs) == 0 {
+		return nil
+	}
+	if !s.aggregate {
+		return s.errs[0]
 	}
-	return w.value, nil
+	return s.errs
 }
 
-func (w *Walker) checkArrayAndLiteral() (*Value, error) {
+// Clone return a new path which removes the relationships with its ancestor.
+// The returned walker is the head of a copy of current walker and the sub-walkers created
+// from it, so registering handlers on either one doesn't affect the other.
+func (w *Walker) Clone() *Walker {
+	head := w.clone(nil)
+	head.aggregate = w.head.aggregate
+	head.keys = nil
+	return head
+}
+
+// clone copies w and its sub-walkers recursively as a sub-walker of parent,
+// or the head if parent is nil.
+func (w *Walker) clone(parent *Walker) *Walker {
+	n := &Walker{
+		parent:             parent,
+		keys:               w.keys,
+		each:               w.each,
+		value:              w.value,
+		valueErr:           w.valueErr,
+		err:                w.err,
+		field:              w.field,
+		compulsoryFields:   append(make([]string, 0, len(w.compulsoryFields)), w.compulsoryFields...),
+		validators:         append([]keyValidator(nil), w.validators...),
+		optionalValidators: append([]keyValidator(nil), w.optionalValidators...),
+		literalValidator:   w.literalValidator,
+		manipulators:       append([]keyManipulator(nil), w.manipulators...),
+	}
+	n.head = n
+	if parent != nil {
+		n.head = parent.head
+	}
+	for _, child := range w.children {
+		n.children = append(n.children, child.clone(n))
+	}
+	return n
+}
+
+// walkPath returns the value by following keys from value, and the count of keys
+// have been followed. A key refers to an element of array by index.
+func walkPath(value *Value, keys []string) (*Value, int, error) {
+	for i, key := range keys {
+		if value == nil {
+			return nil, i, fmt.Errorf("%w: path %s", ErrNilValue, key)
+		}
+		if isDeleted(value) {
+			return nil, i, fmt.Errorf("path %s doesn't exist in a deleted value", key)
+		}
+		var (
+			val *Value
+			ok  bool
+		)
+		switch value.NodeType {
+		case Object:
+			val, ok = value.AstValue.(*ObjectAst).Get(key)
+		case Array:
+			// the key is an array index as json pointer
+			values := GetArrayValues(value)
+			if idx, err := arrayIndex(key, len(values)-1); err == nil {
+				val, ok = &values[idx], true
+			}
+		}
+		if !ok {
+			return nil, i, fmt.Errorf("path %s doesn't exist in nodeype %s", key, value.NodeType)
+		}
+		value = val
+	}
+	return value, len(keys), nil
+}
+
+// walk executes all handlers submitted to it against value at ptr, the failures are
+// reported to state. It returns true if walking should stop.
+func (w *Walker) walk(value *Value, ptr string, state *walkState) bool {
+	if value == nil {
+		return state.report(ptr, nil, ErrNilValue)
+	}
+	// the validator of the value itself runs first for all node types
 	if w.literalValidator != nil {
-		if err := w.literalValidator(w.value); err != nil {
-			return nil, err
+		if err := w.literalValidator(value); err != nil && state.report(ptr, value, err) {
+			return true
 		}
 	}
-	return w.value, nil
+	if value.NodeType == Object {
+		return w.checkObject(value, ptr, state)
+	}
+	return false
 }
 
-func (w *Walker) checkObject() (*Value, error) {
-	objectMap := w.value.AstValue.(*ObjectAst).KvMap
+func (w *Walker) checkObject(value *Value, ptr string, state *walkState) bool {
+	obj := value.AstValue.(*ObjectAst)
 	for _, field := range w.compulsoryFields {
-		if _, ok := objectMap[field]; !ok {
-			return nil, fmt.Errorf("%w: %s", ErrFieldNotExist, field)
+		if _, ok := obj.Get(field); !ok {
+			if state.report(ptr+formatPointer([]string{field}), value, fmt.Errorf("%w: %s", ErrFieldNotExist, field)) {
+				return true
+			}
 		}
 	}
-	for key, validator := range w.optionalValidators {
-		val, ok := objectMap[key]
+	for _, kv := range w.optionalValidators {
+		val, ok := obj.Get(kv.key)
 		// optional fields are allowed
 		if !ok {
 			continue
 		}
-		if err := validator(&val); err != nil {
-			return nil, err
+		if err := kv.validator(val); err != nil && state.report(ptr+formatPointer([]string{kv.key}), val, err) {
+			return true
 		}
 	}
-	for key, validator := range w.validators {
-		// the key for sure exist because of the registering way of Validator
-		val, _ := objectMap[key]
-		if err := validator(&val); err != nil {
-			return nil, err
+	for _, kv := range w.validators {
+		// the key for sure exist because of the registering way of Validator,
+		// unless the missing field has been reported in the aggregate mode
+		val, ok := obj.Get(kv.key)
+		if !ok {
+			continue
+		}
+		if err := kv.validator(val); err != nil && state.report(ptr+formatPointer([]string{kv.key}), val, err) {
+			return true
 		}
 	}
-	return w.value, nil
+	return false
 }
 
 // Path jumps the walker inside the given path of ast.
+// The path is a key of current layer, several keys joined by dot like "a.b.c", or a json
+// pointer(RFC 6901) like "/a/b/c" which helps to refer the key contains dot.
 // If the given path doesn't exist, error is raised when Walk.
-// The scope of Path ends when EndPath is called.
+// The scope of Path ends when EndPath is called, and multiple paths could be created from
+// the same walker, they are walked in order.
 func (w *Walker) Path(path string) *Walker {
-	switch w.value.NodeType {
-	case Object:
-		obj := w.value.AstValue.(*ObjectAst)
-		val, ok := obj.KvMap[path]
-		if ok {
-			n := Walker{
-				head:  w.head,
-				value: &val,
-			}
-			w.next = &n
-			return &n
-		}
-		fallthrough
-	case Number, Null, Array, String, Bool:
-		w.err = fmt.Errorf("path %s doesn't exist in nodeype %s", path, w.value.NodeType)
-		return w
+	n := newWalker(nil)
+	n.head = w.head
+	n.parent = w
+	w.children = append(w.children, n)
+
+	keys, err := splitPath(path)
+	if err != nil {
+		n.err = err
+		return n
 	}
-	return w
+	n.keys = keys
+	// the keys are resolved again when walking, the bound value only helps to walk
+	// from the returned walker by Clone
+	switch {
+	case w.each:
+		n.valueErr = errEachElement
+	case w.value != nil:
+		n.value, _, n.valueErr = walkPath(w.value, keys)
+	default:
+		n.valueErr = w.valueErr
+	}
+	return n
 }
 
-// EndPath returns to the root walker after Path enters a path
+// Index jumps the walker inside the i-th element of the array on current layer,
+// it's same with Path(strconv.Itoa(i)). The scope ends when EndPath is called.
+func (w *Walker) Index(i int) *Walker {
+	return w.Path("/" + strconv.Itoa(i))
+}
+
+// Each applies the returned walker to every element of the array on current layer,
+// the fields, validators, manipulators and paths registered on it are walked for each element.
+// The Path of WalkError reports the index of the failed element. The scope ends when EndPath
+// is called.
+// The walker cloned from the returned one walks every element of the bound array, while the
+// scopes inside it cannot be cloned to walk the bound value as the element is unknown.
+func (w *Walker) Each() *Walker {
+	n := newWalker(nil)
+	n.head = w.head
+	n.parent = w
+	n.each = true
+	if w.each {
+		n.valueErr = errEachElement
+	} else {
+		n.value, n.valueErr = w.value, w.valueErr
+	}
+	w.children = append(w.children, n)
+	return n
+}
+
+// splitPath splits the path of Path into keys.
+func splitPath(path string) ([]string, error) {
+	if strings.HasPrefix(path, "/") {
+		return parsePointer(path)
+	}
+	return strings.Split(path, "."), nil
+}
+
+// EndPath returns to the walker where Path enters a path, or the head walker if current
+// walker isn't created by Path.
 func (w *Walker) EndPath() *Walker {
-	if w.err != nil {
-		w.head.err = w.err
+	if w.parent == nil {
+		return w.head
 	}
-	return w.head
+	return w.parent
 }
@@ -2,63 +2,67 @@ package astjson
 
 import (
 	"errors"
-	"github.com/stretchr/testify/assert"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 var (
 	mixedNode = &Value{
 		NodeType: Object,
-		AstValue: &ObjectAst{map[string]Value{
-			"str":   {NodeType: String, AstValue: StringAst(`123\b\t\r\n`)},
-			"num":   {NodeType: Number, AstValue: NumberAst{Nt: unsignedInteger, u: 123}},
-			"bool":  {NodeType: Bool, AstValue: BoolAst(true)},
-			"null":  {NodeType: Null, AstValue: &NullAst{}},
-			"empty": {NodeType: Object, AstValue: &ObjectAst{map[string]Value{}}},
-			"embed-object": {
+		AstValue: NewObjectAst([]Member{
+			{"str", Value{NodeType: String, AstValue: StringAst(`123\b\t\r\n`)}},
+			{"num", Value{NodeType: Number, AstValue: NumberAst{Nt: unsignedInteger, u: 123}}},
+			{"bool", Value{NodeType: Bool, AstValue: BoolAst(true)}},
+			{"null", Value{NodeType: Null, AstValue: &NullAst{}}},
+			{"empty", Value{NodeType: Object, AstValue: NewObjectAst()}},
+			{"embed-object", Value{
 				NodeType: Object,
-				AstValue: &ObjectAst{map[string]Value{
-					"hello": {NodeType: String, AstValue: StringAst("world")},
-				}}},
-			"array-in-object": {
+				AstValue: NewObjectAst([]Member{
+					{"hello", Value{NodeType: String, AstValue: StringAst("world")}},
+				}...)}},
+			{"array-in-object", Value{
 				NodeType: Object,
-				AstValue: &ObjectAst{map[string]Value{
-					"hello": {
+				AstValue: NewObjectAst([]Member{
+					{"hello", Value{
 						NodeType: Array,
 						AstValue: &ArrayAst{Values: []Value{{NodeType: String, AstValue: StringAst("world")}}}}},
-				}},
-			"array": {
+				}...)}},
+			{"array", Value{
 				NodeType: Array,
 				AstValue: &ArrayAst{[]Value{
 					{NodeType: String, AstValue: StringAst("world")},
 				}},
-			},
-			"empty-array": {
+			}},
+			{"empty-array", Value{
 				NodeType: Array,
 				AstValue: &ArrayAst{},
-			},
-			"embed-empty-array": {
+			}},
+			{"embed-empty-array", Value{
 				NodeType: Array,
 				AstValue: &ArrayAst{[]Value{
 					{NodeType: Array, AstValue: &ArrayAst{}},
 					{NodeType: Array, AstValue: &ArrayAst{}},
 				}},
-			},
-			"array-empty-obj": {
+			}},
+			{"array-empty-obj", Value{
 				NodeType: Array,
 				AstValue: &ArrayAst{[]Value{
-					{NodeType: Object, AstValue: &ObjectAst{KvMap: map[string]Value{}}},
-					{NodeType: Object, AstValue: &ObjectAst{KvMap: map[string]Value{}}},
+					{NodeType: Object, AstValue: NewObjectAst()},
+					{NodeType: Object, AstValue: NewObjectAst()},
 				}},
-			},
-			"array-obj": {
+			}},
+			{"array-obj", Value{
 				NodeType: Array,
 				AstValue: &ArrayAst{[]Value{
-					{NodeType: Object, AstValue: &ObjectAst{map[string]Value{"hello": {NodeType: String, AstValue: StringAst("world")}}}},
-					{NodeType: Object, AstValue: &ObjectAst{map[string]Value{"hello": {NodeType: String, AstValue: StringAst("world")}}}},
+					{NodeType: Object, AstValue: NewObjectAst([]Member{{"hello", Value{NodeType: String, AstValue: StringAst("world")}}}...)},
+					{NodeType: Object, AstValue: NewObjectAst([]Member{{"hello", Value{NodeType: String, AstValue: StringAst("world")}}}...)},
 				}},
-			},
-		}},
+			}},
+		}...),
 	}
 )
 
@@ -310,7 +314,7 @@ func Test_WalkTopLevel_LiteralAndArray(t *testing.T) {
 func Test_WalkTopLevel_Object_Empty(t *testing.T) {
 	input := &Value{
 		NodeType: Object,
-		AstValue: &ObjectAst{map[string]Value{}},
+		AstValue: NewObjectAst(),
 	}
 
 	val, err := NewWalker(input).
@@ -389,7 +393,7 @@ func Test_WalkTopLevel_Object_Mixed(t *testing.T) {
 		Field("str").
 		Optional("num", shouldBe234).Walk()
 
-	assert.Equal(t, "num should be 234", err.Error())
+	assert.Equal(t, "/num: num should be 234", err.Error())
 	assert.Nil(t, val)
 
 	val, err = NewWalker(mixedNode).
@@ -397,14 +401,14 @@ func Test_WalkTopLevel_Object_Mixed(t *testing.T) {
 		Field("num").Validate(shouldBe234).
 		Walk()
 
-	assert.Equal(t, "num should be 234", err.Error())
+	assert.Equal(t, "/num: num should be 234", err.Error())
 	assert.Nil(t, val)
 
 	val, err = NewWalker(mixedNode).
 		Field("str").
 		ValidateKey("num", shouldBe234).Walk()
 
-	assert.Equal(t, "num should be 234", err.Error())
+	assert.Equal(t, "/num: num should be 234", err.Error())
 	assert.Nil(t, val)
 
 }
@@ -412,17 +416,19 @@ func Test_WalkTopLevel_Object_Mixed(t *testing.T) {
 func Test_WalkPath_and_EndPath(t *testing.T) {
 	input := &Value{
 		NodeType: Object,
-		AstValue: &ObjectAst{map[string]Value{}},
+		AstValue: NewObjectAst(),
 	}
 	w := NewWalker(input)
 
-	assert.NotNil(t, w.Path("").err)
+	assert.NotNil(t, w.Path("").valueErr)
 	val, err := w.Path("").Walk()
 	assert.NotNil(t, err)
 	assert.Nil(t, val)
 
-	assert.NotNil(t, w.Path("123").err)
-	assert.NotNil(t, w.Path("123").EndPath().err)
+	// the missing path doesn't affect its parent
+	assert.NotNil(t, w.Path("123").valueErr)
+	assert.NoError(t, w.Path("123").EndPath().err)
+	assert.NoError(t, w.Path("123").EndPath().valueErr)
 
 	w = NewWalker(mixedNode)
 	nw := w.Path("str")
@@ -432,12 +438,92 @@ func Test_WalkPath_and_EndPath(t *testing.T) {
 	assert.Equal(t, w, nw.EndPath())
 
 	w = NewWalker(mixedNode)
-	nw = w.Path("embed-object").Path("hello")
+	embed := w.Path("embed-object")
+	nw = embed.Path("hello")
 	assert.NoError(t, w.err)
 	strNode = &Value{NodeType: String, AstValue: StringAst("world")}
 	assert.Equal(t, strNode, nw.value)
-	assert.Equal(t, w, nw.EndPath())
-	assert.Equal(t, mixedNode, nw.EndPath().value)
+	// EndPath returns to the parent scope
+	assert.Equal(t, embed, nw.EndPath())
+	assert.Equal(t, w, nw.EndPath().EndPath())
+	assert.Equal(t, mixedNode, nw.EndPath().EndPath().value)
+	assert.Equal(t, w, w.EndPath())
+
+	// dotted and pointer shorthand
+	w = NewWalker(mixedNode)
+	assert.Equal(t, strNode, w.Path("embed-object.hello").value)
+	assert.Equal(t, strNode, w.Path("/embed-object/hello").value)
+	assert.Equal(t, w, w.Path("embed-object.hello").EndPath())
+	assert.NoError(t, w.err)
+	assert.NotNil(t, w.Path("embed-object.non-exist").valueErr)
+	assert.ErrorIs(t, NewWalker(nil).Path("/a~2").err, ErrInvalidPointer)
+}
+
+func Test_WalkPath_MissingOnBoundValue(t *testing.T) {
+	docA := Parse([]byte(`{"name": "a"}`))
+	docB := Parse([]byte(`{"name": "a", "sub": {"num": 123}}`))
+
+	// the path missing in the bound value stays in the plan
+	w := NewWalker(docA).Field("name").
+		Path("sub").ValidateKey("num", shouldBe123).EndPath().
+		ValidateKey("name", ShouldNotEqualString(""))
+	_, err := w.Walk()
+	assert.EqualError(t, err, "/sub: path sub doesn't exist in nodeype Object")
+
+	val, err := w.WalkValue(docB)
+	assert.NoError(t, err)
+	assert.Equal(t, docB, val)
+	_, err = w.Clone().WalkValue(docB)
+	assert.NoError(t, err)
+
+	// the validators after the missing path belong to its scope instead of the parent
+	sub := NewWalker(docA).Path("sub").ValidateKey("num", shouldBe123)
+	assert.Empty(t, sub.EndPath().validators)
+	_, err = sub.Clone().Walk()
+	assert.EqualError(t, err, "path sub doesn't exist in nodeype Object")
+	subB := GetObjectKvMap(docB)["sub"]
+	_, err = sub.Clone().WalkValue(&subB)
+	assert.NoError(t, err)
+}
+
+func Test_Walk_NestedPath(t *testing.T) {
+	input := Parse([]byte(`{
+		"a": {"b": {"c": 123}, "d": {"e": true}},
+		"f.g": {"h": "world"},
+		"i": {"j": 1}
+	}`))
+	var visited []string
+	visit := func(name string, validator Validator) Validator {
+		return func(value *Value) error {
+			visited = append(visited, name)
+			return validator(value)
+		}
+	}
+	w := NewWalker(input).
+		Path("a").
+		Path("b").ValidateKey("c", visit("c", shouldBe123)).EndPath().
+		Path("d").ValidateKey("e", visit("e", shouldBeTrue)).EndPath().
+		Field("b").
+		EndPath().
+		Path("/f.g").ValidateKey("h", visit("h", shouldBeWorld)).EndPath().
+		Path("a.b.c").Validate(visit("a.b.c", shouldBe123)).EndPath().
+		Path("i").ValidateKey("j", visit("j", shouldBe123)).EndPath()
+
+	val, err := w.Walk()
+	assert.EqualError(t, err, "/i/j: num should be 123")
+	assert.Nil(t, val)
+	assert.Equal(t, []string{"c", "e", "h", "a.b.c", "j"}, visited)
+
+	visited = nil
+	_, err = w.Clone().Aggregate().WalkValue(Parse([]byte(`{
+		"a": {"b": {"c": 1}, "d": {"e": true}},
+		"f.g": {"h": "world"},
+		"i": {}
+	}`)))
+	var errs WalkErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, []string{"/a/b/c", "/a/b/c", "/i/j"}, walkErrorPaths(errs))
+	assert.Equal(t, []string{"c", "e", "h", "a.b.c"}, visited)
 }
 
 func Test_Walk_Object_Mixed(t *testing.T) {
@@ -457,8 +543,104 @@ func Test_Walk_Object_Mixed(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func Test_Walk_HomogeneousArray(t *testing.T) {
+	_, err := NewWalker(Parse([]byte(`[1, 2, 3]`))).Validate(ShouldBeHomogeneousArray()).Walk()
+	assert.NoError(t, err)
+
+	_, err = NewWalker(Parse([]byte(`[]`))).Validate(ShouldBeHomogeneousArray()).Walk()
+	assert.NoError(t, err)
+
+	_, err = NewWalker(Parse([]byte(`{"arr": [1, "2"]}`))).
+		ValidateKey("arr", ShouldBeHomogeneousArray()).Walk()
+	assert.ErrorIs(t, err, ErrInconsistentArray)
+	assert.EqualError(t, err, "/arr: inconsistent array value type: element 1 is String, expected Number")
+
+	_, err = NewWalker(Parse([]byte(`1`))).Validate(ShouldBeHomogeneousArray()).Walk()
+	assert.Error(t, err)
+}
+
 func Test_WalkerClone(t *testing.T) {
-	assert.Nil(t, NewWalker(nil).Clone())
+	assert.NotNil(t, NewWalker(nil).Clone())
+
+	w := NewWalker(mixedNode).Field("str")
+	sub := w.Path("embed-object").Field("hello")
+	cloned := w.Clone()
+	cloned.Field("non-exist")
+
+	val, err := w.Walk()
+	assert.NoError(t, err)
+	assert.Equal(t, mixedNode, val)
+	_, err = cloned.Walk()
+	assert.ErrorIs(t, err, ErrFieldNotExist)
+
+	// walk from the sub-walker as the very beginning
+	embed, _ := GetObject(mixedNode).Get("embed-object")
+	subCloned := sub.Clone()
+	val, err = subCloned.Walk()
+	assert.NoError(t, err)
+	assert.Equal(t, embed, val)
+	_, err = subCloned.Field("non-exist").Walk()
+	assert.ErrorIs(t, err, ErrFieldNotExist)
+
+	// the original walker isn't affected
+	val, err = w.Walk()
+	assert.NoError(t, err)
+	assert.Equal(t, mixedNode, val)
+}
+
+func Test_WalkValue(t *testing.T) {
+	plan := NewWalker(nil).
+		Field("name").Validate(ShouldNotEqualString("")).
+		Optional("age", shouldBe123).
+		Path("address").Field("city").EndPath()
+
+	_, err := plan.Walk()
+	assert.ErrorIs(t, err, ErrNilValue)
+
+	testCases := map[string]struct {
+		input  string
+		errStr string
+	}{
+		"pass":              {input: `{"name": "a", "age": 123, "address": {"city": "b"}}`},
+		"optional absent":   {input: `{"name": "a", "address": {"city": "b"}}`},
+		"missing field":     {input: `{"age": 123, "address": {"city": "b"}}`, errStr: "/name: field not exist: name"},
+		"invalid optional":  {input: `{"name": "a", "age": 1, "address": {"city": "b"}}`, errStr: "/age: num should be 123"},
+		"missing path":      {input: `{"name": "a"}`, errStr: "/address: path address doesn't exist in nodeype Object"},
+		"missing sub field": {input: `{"name": "a", "address": {}}`, errStr: "/address/city: field not exist: city"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			input := Parse([]byte(tc.input))
+			val, err := plan.WalkValue(input)
+			if tc.errStr != "" {
+				assert.EqualError(t, err, tc.errStr)
+				assert.Nil(t, val)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, input, val)
+		})
+	}
+}
+
+func Test_WalkValue_Concurrently(t *testing.T) {
+	plan := NewWalker(nil).Field("id").Path("payload").Field("ok").Validate(ShouldEqualTrue()).EndPath()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			input := Parse([]byte(fmt.Sprintf(`{"id": %d, "payload": {"ok": %t}}`, i, i%2 == 0)))
+			_, err := plan.WalkValue(input)
+			if i%2 == 0 {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, "/payload/ok: value should be true")
+			}
+		}(i)
+	}
+	wg.Wait()
 }
 func shouldBeWorld(value *Value) error {
 	if value.AstValue.(StringAst) != "world" {
@@ -468,7 +650,7 @@ func shouldBeWorld(value *Value) error {
 }
 
 func shouldBeObject(value *Value) error {
-	if value.AstValue != Object {
+	if value.NodeType != Object {
 		return errors.New("value should be an object")
 	}
 	return nil
@@ -496,3 +678,329 @@ func shouldBeTrue(value *Value) error {
 	}
 	return errors.New("bool should be true")
 }
+
+func Test_Walk_Aggregate(t *testing.T) {
+	plan := NewWalker(nil).Aggregate().
+		Field("name").Validate(ShouldNotEqualString("")).
+		Optional("age", shouldBe123).
+		Field("enabled").Validate(shouldBeTrue).
+		Path("address").Field("city").Field("street").
+		Path("geo.lat").Validate(shouldBe123).EndPath().
+		EndPath()
+
+	_, err := plan.WalkValue(Parse([]byte(`{"name": "", "age": 1, "address": {"street": "s"}}`)))
+	var errs WalkErrors
+	assert.True(t, errors.As(err, &errs))
+	// the missing path is reported once although its sub-walker fails as well
+	assert.Equal(t, []string{"/enabled", "/age", "/name", "/address/city", "/address/geo"}, walkErrorPaths(errs))
+	assert.ErrorIs(t, errs[0], ErrFieldNotExist)
+	assert.ErrorIs(t, errs[3], ErrFieldNotExist)
+	assert.EqualError(t, errs[1], "/age: num should be 123")
+	assert.EqualError(t, errs[2], "/name: value is , equal with expected value")
+	// the errors inside are checked by the Is and As methods without relying on go 1.20
+	assert.True(t, errs.Is(ErrFieldNotExist))
+	assert.False(t, errs.Is(ErrNilValue))
+	var walkErr *WalkError
+	assert.True(t, errs.As(&walkErr))
+	assert.Equal(t, "/enabled", walkErr.Path)
+
+	// the order is deterministic
+	for i := 0; i < 10; i++ {
+		_, again := plan.WalkValue(Parse([]byte(`{"name": "", "age": 1, "address": {"street": "s"}}`)))
+		assert.Equal(t, err, again)
+	}
+
+	val, err := plan.Clone().WalkValue(Parse([]byte(`{"name": "a", "enabled": true, "address": {"city": "c", "street": "s", "geo": {"lat": 123}}}`)))
+	assert.NoError(t, err)
+	assert.NotNil(t, val)
+
+	// the first error is returned alone without the aggregate mode
+	_, err = NewWalker(Parse([]byte(`{"age": 1}`))).Field("name").Optional("age", shouldBe123).Walk()
+	assert.EqualError(t, err, "/name: field not exist: name")
+}
+
+func Test_Walk_Aggregate_PathErrors(t *testing.T) {
+	input := Parse([]byte(`{"name": "", "sub": {"num": 1}, "arr": [1]}`))
+	_, err := NewWalker(input).Aggregate().
+		Path("missing").ValidateKey("num", shouldBe123).EndPath().
+		Path("/a~2").ValidateKey("num", shouldBe123).EndPath().
+		Path("sub").ValidateKey("num", shouldBe123).EndPath().
+		Path("arr").Index(3).EndPath().EndPath().
+		ValidateKey("name", ShouldNotEqualString("")).
+		Walk()
+	var errs WalkErrors
+	assert.True(t, errors.As(err, &errs))
+	// the failed paths don't hide the validators and paths after them
+	assert.Equal(t, []string{"/name", "/missing", "", "/sub/num", "/arr/3"}, walkErrorPaths(errs))
+	assert.EqualError(t, errs[1], "/missing: path missing doesn't exist in nodeype Object")
+	assert.ErrorIs(t, errs[2], ErrInvalidPointer)
+	assert.EqualError(t, errs[4], "/arr/3: path 3 doesn't exist in nodeype Array")
+}
+
+func Test_Walk_ErrorSpan(t *testing.T) {
+	input := Parse([]byte(`{"a": {"b": 1}}`), WithSpans())
+	_, err := NewWalker(input).Path("a").ValidateKey("b", shouldBe123).EndPath().Walk()
+	var walkErr *WalkError
+	if assert.True(t, errors.As(err, &walkErr)) && assert.NotNil(t, walkErr.Span) {
+		assert.Equal(t, "/a/b", walkErr.Path)
+		assert.Equal(t, Position{Offset: 12, Line: 1, Column: 13}, walkErr.Span.Start)
+	}
+	assert.EqualError(t, err, "/a/b (line 1, column 13): num should be 123")
+
+	// the missing value is located by its parent
+	_, err = NewWalker(input).Aggregate().Path("a").Field("c").EndPath().Walk()
+	var errs WalkErrors
+	if assert.True(t, errors.As(err, &errs)) && assert.Len(t, errs, 1) {
+		assert.Equal(t, Position{Offset: 6, Line: 1, Column: 7}, errs[0].Span.Start)
+		assert.EqualError(t, errs[0], "/a/c (line 1, column 7): field not exist: c")
+	}
+
+	_, err = NewWalker(input).Validate(func(*Value) error { return errors.New("root") }).Walk()
+	assert.EqualError(t, err, "(line 1, column 1): root")
+
+	// no location is reported without spans
+	_, err = NewWalker(Parse([]byte(`{"a": {"b": 1}}`))).Path("a").ValidateKey("b", shouldBe123).EndPath().Walk()
+	assert.EqualError(t, err, "/a/b: num should be 123")
+}
+
+func Test_Walk_Validators_Order(t *testing.T) {
+	var order []string
+	record := func(key string) Validator {
+		return func(value *Value) error {
+			order = append(order, key)
+			return nil
+		}
+	}
+	w := NewWalker(mixedNode).
+		ValidateKey("str", record("str")).
+		ValidateKey("num", record("num")).
+		ValidateKey("bool", record("bool")).
+		ValidateKey("null", record("null")).
+		// override the validator in place
+		ValidateKey("num", record("num2"))
+	for i := 0; i < 10; i++ {
+		order = nil
+		_, err := w.Walk()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"str", "num2", "bool", "null"}, order)
+	}
+}
+
+func walkErrorPaths(errs WalkErrors) []string {
+	var paths []string
+	for _, err := range errs {
+		paths = append(paths, err.Path)
+	}
+	return paths
+}
+
+func Test_Walk_Manipulate(t *testing.T) {
+	trim := func(value *Value) {
+		value.AstValue = StringAst(strings.TrimSpace(GetString(value)))
+	}
+	defaultRole := func(value *Value) {
+		if value.AstValue == nil {
+			*value = Value{NodeType: String, AstValue: StringAst("guest")}
+		}
+	}
+
+	plan := NewWalker(nil).
+		Field("name").Validate(ShouldNotEqualString("")).Manipulate(trim).
+		ManipulateKey("role", defaultRole).
+		ManipulateKey("password", DeleteValue).
+		Path("profile").ManipulateKey("token", DeleteValue).EndPath()
+
+	input := Parse([]byte(`{"name": "  alice ", "password": "secret", "profile": {"age": 1, "token": "t"}}`))
+	val, err := plan.WalkValue(input)
+	assert.NoError(t, err)
+	assert.Equal(t, input, val)
+	bs, err := Marshal(val)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"alice","profile":{"age":1},"role":"guest"}`, string(bs))
+
+	// the existing value is kept
+	input = Parse([]byte(`{"name": "bob", "role": "admin", "profile": {}}`))
+	_, err = plan.WalkValue(input)
+	assert.NoError(t, err)
+	bs, _ = Marshal(input)
+	assert.Equal(t, `{"name":"bob","role":"admin","profile":{}}`, string(bs))
+
+	// manipulators aren't executed if validators fail
+	input = Parse([]byte(`{"name": "", "password": "secret", "profile": {}}`))
+	_, err = plan.WalkValue(input)
+	assert.Error(t, err)
+	bs, _ = Marshal(input)
+	assert.Equal(t, `{"name":"","password":"secret","profile":{}}`, string(bs))
+}
+
+func Test_Walk_Manipulate_Layer(t *testing.T) {
+	input := Parse([]byte(`{"a": {"b": {"c": 1}}, "d": 2}`))
+	_, err := NewWalker(input).
+		Path("a").Manipulate(DeleteValue).Path("b").Manipulate(func(value *Value) {
+		t.Fatal("the sub-walkers of the deleted value should be skipped")
+	}).EndPath().EndPath().
+		Path("a.b").ManipulateKey("c", DeleteValue).EndPath().
+		Walk()
+	assert.NoError(t, err)
+	bs, _ := Marshal(input)
+	assert.Equal(t, `{"d":2}`, string(bs))
+
+	input = Parse([]byte(`{"a": 1}`))
+	val, err := NewWalker(input).Manipulate(func(value *Value) {
+		*value = *Parse([]byte(`[true]`))
+	}).Walk()
+	assert.NoError(t, err)
+	assert.Equal(t, Parse([]byte(`[true]`)), val)
+
+	// the walked value cannot be deleted, it's reset to the one before the deleting Manipulator
+	input = Parse([]byte(`{"a": 1}`))
+	val, err = NewWalker(input).ManipulateKey("b", func(value *Value) {
+		*value = *Parse([]byte(`2`))
+	}).Manipulate(DeleteValue).Walk()
+	assert.ErrorIs(t, err, ErrDeleteRoot)
+	assert.Nil(t, val)
+	bs, _ = Marshal(input)
+	assert.Equal(t, `{"a":1,"b":2}`, string(bs))
+}
+
+func Test_Walk_Manipulate_Failure(t *testing.T) {
+	// the manipulators on the former paths don't run if the latter validators fail
+	input := Parse([]byte(`{"a": {"b": 1}, "items": [1, 2, 3]}`))
+	_, err := NewWalker(input).
+		Path("a").ManipulateKey("b", DeleteValue).EndPath().
+		Path("items").Index(0).Manipulate(DeleteValue).EndPath().EndPath().
+		Path("items").Index(2).Validate(shouldBe123).EndPath().EndPath().
+		Walk()
+	assert.EqualError(t, err, "/items/2: num should be 123")
+	bs, _ := Marshal(input)
+	assert.Equal(t, `{"a":{"b":1},"items":[1,2,3]}`, string(bs))
+
+	// the validators see the value as it's given instead of the manipulated one
+	input = Parse([]byte(`{"name": " a "}`))
+	_, err = NewWalker(input).
+		Manipulate(func(value *Value) {
+			GetObject(value).Set("name", Value{NodeType: String, AstValue: StringAst("")})
+		}).
+		ValidateKey("name", ShouldNotEqualString("")).
+		Walk()
+	assert.NoError(t, err)
+}
+
+func Test_Walk_Manipulate_DeleteIndex(t *testing.T) {
+	// deleting an element doesn't move the elements referred by the latter paths
+	input := Parse([]byte(`{"items": [{"id": 0}, {"id": 1, "tmp": true}, {"id": 2}, {"id": 3}]}`))
+	_, err := NewWalker(input).
+		Path("items").
+		Index(0).Manipulate(DeleteValue).EndPath().
+		Index(1).ManipulateKey("tmp", DeleteValue).EndPath().
+		Index(2).Manipulate(DeleteValue).EndPath().
+		Path("/3/id").Manipulate(DeleteValue).EndPath().
+		EndPath().
+		Walk()
+	assert.NoError(t, err)
+	bs, _ := Marshal(input)
+	assert.Equal(t, `{"items":[{"id":1},{}]}`, string(bs))
+
+	// the deleted elements of each are removed together with the ones of paths
+	input = Parse([]byte(`[[1, 2], [3], [4, 5]]`))
+	_, err = NewWalker(input).
+		Index(0).Each().Manipulate(DeleteValue).EndPath().EndPath().
+		Index(1).Manipulate(DeleteValue).EndPath().
+		Path("/2/0").Manipulate(DeleteValue).EndPath().
+		Walk()
+	assert.NoError(t, err)
+	bs, _ = Marshal(input)
+	assert.Equal(t, `[[],[5]]`, string(bs))
+}
+
+func Test_Walk_Index(t *testing.T) {
+	input := Parse([]byte(`{"items": [{"name": "a"}, {"name": "world"}], "matrix": [[1], [2, 123]]}`))
+	val, err := NewWalker(input).
+		Path("items").Index(1).ValidateKey("name", shouldBeWorld).EndPath().EndPath().
+		Path("matrix.1.1").Validate(shouldBe123).EndPath().
+		Path("/matrix/0").Index(0).Validate(shouldBe234).EndPath().EndPath().
+		Walk()
+	assert.EqualError(t, err, "/matrix/0/0: num should be 234")
+	assert.Nil(t, val)
+
+	w := NewWalker(input).Path("items").Index(2)
+	assert.EqualError(t, w.valueErr, "path 2 doesn't exist in nodeype Array")
+	_, err = w.Walk()
+	assert.EqualError(t, err, "/items/2: path 2 doesn't exist in nodeype Array")
+
+	_, err = NewWalker(nil).Aggregate().Path("items").Index(5).EndPath().EndPath().WalkValue(input)
+	assert.EqualError(t, err, "/items/5: path 5 doesn't exist in nodeype Array")
+}
+
+func Test_Walk_Each(t *testing.T) {
+	plan := NewWalker(nil).
+		Path("items").Each().
+		Field("name").Validate(ShouldNotEqualString("")).
+		Optional("qty", shouldBe123).
+		Path("tags").Each().Validate(ShouldNotEqualString("")).EndPath().EndPath().
+		EndPath().EndPath()
+
+	input := `{"items": [
+		{"name": "a", "tags": ["x"]},
+		{"name": "b", "qty": 1, "tags": []},
+		{"tags": ["y", ""]}
+	]}`
+	_, err := plan.WalkValue(Parse([]byte(input)))
+	assert.EqualError(t, err, "/items/1/qty: num should be 123")
+
+	_, err = plan.Clone().Aggregate().WalkValue(Parse([]byte(input)))
+	var errs WalkErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, []string{"/items/1/qty", "/items/2/name", "/items/2/tags/1"}, walkErrorPaths(errs))
+
+	_, err = plan.WalkValue(Parse([]byte(`{"items": [{"name": "a", "tags": ["", "x"]}]}`)))
+	assert.EqualError(t, err, "/items/0/tags/0: value is , equal with expected value")
+
+	_, err = plan.WalkValue(Parse([]byte(`{"items": {}}`)))
+	assert.EqualError(t, err, "/items: cannot walk each element of nodeype Object")
+
+	val, err := plan.WalkValue(Parse([]byte(`{"items": []}`)))
+	assert.NoError(t, err)
+	assert.NotNil(t, val)
+}
+
+func Test_Walk_Each_Manipulate(t *testing.T) {
+	input := Parse([]byte(`{"items": [{"secret": true}, {"id": 1}, {"secret": true}, {"id": 2}]}`))
+	_, err := NewWalker(input).
+		Path("items").Each().Manipulate(func(value *Value) {
+		if _, ok := GetObject(value).Get("secret"); ok {
+			DeleteValue(value)
+		}
+	}).EndPath().EndPath().Walk()
+	assert.NoError(t, err)
+	bs, _ := Marshal(input)
+	assert.Equal(t, `{"items":[{"id":1},{"id":2}]}`, string(bs))
+}
+
+func Test_Walk_Each_Clone(t *testing.T) {
+	input := Parse([]byte(`{"items": [{"id": 1}, {"id": 2, "tmp": true}]}`))
+
+	// the walker cloned from Each walks every element of the bound array
+	val, err := NewWalker(input).Path("items").Each().Field("id").Clone().Walk()
+	assert.NoError(t, err)
+	items, _ := GetObject(input).Get("items")
+	assert.Equal(t, items, val)
+
+	_, err = NewWalker(input).Path("items").Each().Field("name").Clone().Walk()
+	assert.EqualError(t, err, "/0/name: field not exist: name")
+	_, err = NewWalker(input).Path("items").Each().Field("name").Clone().Aggregate().Walk()
+	var errs WalkErrors
+	assert.True(t, errors.As(err, &errs))
+	assert.Equal(t, []string{"/0/name", "/1/name"}, walkErrorPaths(errs))
+
+	_, err = NewWalker(input).Path("items").Each().ManipulateKey("tmp", DeleteValue).Clone().Walk()
+	assert.NoError(t, err)
+	bs, _ := Marshal(input)
+	assert.Equal(t, `{"items":[{"id":1},{"id":2}]}`, string(bs))
+
+	// the scopes inside Each don't know which element to walk
+	_, err = NewWalker(input).Path("items").Each().Path("id").Clone().Walk()
+	assert.ErrorIs(t, err, ErrNilValue)
+	_, err = NewWalker(input).Path("items").Each().Each().Clone().Walk()
+	assert.ErrorIs(t, err, ErrNilValue)
+}